@@ -0,0 +1,98 @@
+// controllers/clusterconfigmapsource_controller.go
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+// ClusterConfigMapSourceReconciler reconciles a ClusterConfigMapSource object
+// It syncs with the same code as ConfigMapSourceReconciler, and shares its Git cache
+// unless one is set
+type ClusterConfigMapSourceReconciler struct {
+	ConfigMapSourceReconciler
+}
+
+// +kubebuilder:rbac:groups=config.example.com,resources=clusterconfigmapsources,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=config.example.com,resources=clusterconfigmapsources/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=config.example.com,resources=clusterconfigmapsources/finalizers,verbs=update
+
+// Reconcile handles ClusterConfigMapSource resources
+func (r *ClusterConfigMapSourceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("Reconciling ClusterConfigMapSource", "request", req.NamespacedName)
+
+	// Fetch the ClusterConfigMapSource instance
+	var clusterConfigMapSource configv1alpha1.ClusterConfigMapSource
+	if err := r.Get(ctx, req.NamespacedName, &clusterConfigMapSource); err != nil {
+		if apierrors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request
+			logger.Info("ClusterConfigMapSource resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request
+		logger.Error(err, "Failed to get ClusterConfigMapSource")
+		return ctrl.Result{}, err
+	}
+
+	return r.reconcileSource(ctx, &clusterConfigMapSource)
+}
+
+// newClusterConfigMapSourceList returns an empty ClusterConfigMapSourceList
+func newClusterConfigMapSourceList() client.ObjectList {
+	return &configv1alpha1.ClusterConfigMapSourceList{}
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ClusterConfigMapSourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.GitCache == nil {
+		gitCache, err := sharedGitCache()
+		if err != nil {
+			return err
+		}
+		r.GitCache = gitCache
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("clusterconfigmapsource-controller")
+	}
+	r.Recorder = newDedupRecorder(r.Recorder, eventDedupWindow)
+
+	// Report the sources that exist at startup before they are reconciled
+	if err := mgr.Add(&sourceStateSeeder{reader: mgr.GetAPIReader(), newList: newClusterConfigMapSourceList}); err != nil {
+		return err
+	}
+
+	// Index ClusterConfigMapSources by the ConfigMap or Secret they read from, and by UID
+	// so their targets, which are tracked by label, can be mapped back to them
+	ctx := context.Background()
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &configv1alpha1.ClusterConfigMapSource{}, sourceConfigMapIndex, sourceConfigMapIndexValue); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &configv1alpha1.ClusterConfigMapSource{}, sourceSecretIndex, sourceSecretIndexValue); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &configv1alpha1.ClusterConfigMapSource{}, ownerUIDIndex, ownerUIDIndexValue); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// Status updates don't change the generation, so they don't queue another reconcile
+		// Labels are passed to templates, so changing them does
+		For(&configv1alpha1.ClusterConfigMapSource{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.requestsForOwnerLabel(newClusterConfigMapSourceList))).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.requestsForIndex(newClusterConfigMapSourceList, sourceConfigMapIndex))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.requestsForIndex(newClusterConfigMapSourceList, sourceSecretIndex))).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.requestsForNamespace(newClusterConfigMapSourceList))).
+		Complete(r)
+}
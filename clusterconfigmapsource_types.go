@@ -0,0 +1,61 @@
+// api/v1alpha1/clusterconfigmapsource_types.go
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GenericConfigMapSource is implemented by ConfigMapSource and ClusterConfigMapSource,
+// so both kinds can be synced by the same code
+// +kubebuilder:object:generate=false
+type GenericConfigMapSource interface {
+	client.Object
+
+	GetSpec() *ConfigMapSourceSpec
+	GetStatus() *ConfigMapSourceStatus
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Source Type",type="string",JSONPath=".spec.sourceType"
+// +kubebuilder:printcolumn:name="Target ConfigMap",type="string",JSONPath=".spec.targetConfigMap"
+// +kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime"
+// +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.sourceRevision.commit",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterConfigMapSource is the Schema for the clusterconfigmapsources API
+// It has the same spec as ConfigMapSource, but is cluster-scoped, so source and
+// target namespaces must always be set explicitly
+type ClusterConfigMapSource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ConfigMapSourceSpec   `json:"spec,omitempty"`
+	Status ConfigMapSourceStatus `json:"status,omitempty"`
+}
+
+// GetSpec returns the spec of the ClusterConfigMapSource
+func (c *ClusterConfigMapSource) GetSpec() *ConfigMapSourceSpec {
+	return &c.Spec
+}
+
+// GetStatus returns the status of the ClusterConfigMapSource
+func (c *ClusterConfigMapSource) GetStatus() *ConfigMapSourceStatus {
+	return &c.Status
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterConfigMapSourceList contains a list of ClusterConfigMapSource
+type ClusterConfigMapSourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterConfigMapSource `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterConfigMapSource{}, &ClusterConfigMapSourceList{})
+}
@@ -10,9 +10,10 @@ import (
 type ConfigMapSourceSpec struct {
 	// SourceType specifies the type of source to fetch the configuration from
 	// Valid values are: "Git", "File", "ConfigMap", "Secret"
+	// Either SourceType or Sources must be set
 	// +kubebuilder:validation:Enum=Git;File;ConfigMap;Secret
-	// +kubebuilder:validation:Required
-	SourceType string `json:"sourceType"`
+	// +optional
+	SourceType string `json:"sourceType,omitempty"`
 
 	// Git source configuration
 	// +optional
@@ -30,20 +31,228 @@ type ConfigMapSourceSpec struct {
 	// +optional
 	Secret *SecretSource `json:"secret,omitempty"`
 
+	// Sources is an ordered list of sources merged into the target ConfigMap
+	// When several sources provide the same key, the later source in the list wins
+	// and the key is reported in status.keyConflicts
+	// Cannot be combined with SourceType
+	// +optional
+	Sources []SourceSpec `json:"sources,omitempty"`
+
 	// TargetConfigMap is the name of the ConfigMap to be created/updated
 	// +kubebuilder:validation:Required
 	TargetConfigMap string `json:"targetConfigMap"`
 
 	// TargetNamespace is the namespace where the target ConfigMap will be created
 	// If not specified, the same namespace as the ConfigMapSource will be used
+	// A ConfigMapSource can only name its own namespace
 	// +optional
 	TargetNamespace string `json:"targetNamespace,omitempty"`
 
+	// TargetNamespaces lists further namespaces the target ConfigMap is created in
+	// A ConfigMapSource can only name its own namespace
+	// +optional
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+
+	// TargetNamespaceSelector selects namespaces by label to create the target ConfigMap in
+	// Namespaces that gain the labels get the target, and the target is cleaned up
+	// from namespaces that lose them
+	// If none of TargetNamespace, TargetNamespaces and TargetNamespaceSelector is set,
+	// the same namespace as the ConfigMapSource will be used
+	// Only a ClusterConfigMapSource can select namespaces
+	// +optional
+	TargetNamespaceSelector *metav1.LabelSelector `json:"targetNamespaceSelector,omitempty"`
+
 	// RefreshInterval is the interval in seconds to sync the ConfigMap with the source
 	// If not specified or set to 0, no automatic refresh will be performed
 	// +optional
 	// +kubebuilder:validation:Minimum=0
 	RefreshInterval *int64 `json:"refreshInterval,omitempty"`
+
+	// MergeStrategy specifies how the source data is combined with keys already in the target ConfigMap
+	// Valid values are: "Replace" (the target holds exactly the source keys),
+	// "MergePreserveUnmanaged" (keys not written by this source are kept, source keys take precedence),
+	// "MergeFailOnConflict" (like MergePreserveUnmanaged, but fails with a Conflict condition if a source key
+	// is owned by another field manager with a different value)
+	// The target is written with server-side apply under a field manager per ConfigMapSource, so the API server
+	// tracks the keys written by each source and prunes keys removed from the source
+	// +kubebuilder:validation:Enum=Replace;MergePreserveUnmanaged;MergeFailOnConflict
+	// +kubebuilder:default=Replace
+	// +optional
+	MergeStrategy string `json:"mergeStrategy,omitempty"`
+
+	// ConflictPolicy specifies what to do when the target ConfigMap already exists and is not owned by this source,
+	// for example because it was created by Helm or by hand
+	// Valid values are: "Fail" (do not write to it and set the TargetConflict condition),
+	// "Adopt" (take ownership of it, unless it is owned by another source or controller),
+	// "Overwrite" (write to it without taking ownership)
+	// A target annotated with configmapsource.config.example.com/adopt set to the kind, namespace and name
+	// of this source, e.g. ConfigMapSource/team-a/ca-bundle, is adopted regardless of the policy
+	// With a merge strategy, targets owned by another source are shared regardless of the policy,
+	// unless that source uses the Replace strategy
+	// +kubebuilder:validation:Enum=Fail;Adopt;Overwrite
+	// +kubebuilder:default=Fail
+	// +optional
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+
+	// DeletionPolicy specifies what happens to owned target ConfigMaps when the ConfigMapSource is deleted
+	// or a namespace is no longer targeted
+	// Valid values are: "Delete" (delete them), "Orphan" (leave them in place with their current content,
+	// for example for running pods), "RestoreOriginal" (put back the content adopted targets had before
+	// adoption, targets created by this source are deleted)
+	// +kubebuilder:validation:Enum=Delete;Orphan;RestoreOriginal
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// DriftPolicy specifies what to do when the target ConfigMap no longer matches the source,
+	// for example after a manual edit
+	// Valid values are: "Correct" (re-apply the source content), "Report" (only set the Drifted condition), "Ignore"
+	// +kubebuilder:validation:Enum=Correct;Report;Ignore
+	// +kubebuilder:default=Correct
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// RolloutTargets lists workloads consuming the target ConfigMap that are restarted when its content changes
+	// The pod template of each workload is annotated with the hash of the synced content
+	// With Immutable set, workloads keep the ConfigMap they reference and are not restarted
+	// +optional
+	RolloutTargets []RolloutTarget `json:"rolloutTargets,omitempty"`
+
+	// Immutable writes the content to immutable ConfigMaps named after the target ConfigMap and the
+	// hash of the content, e.g. app-config-3f2a9c1b7e, instead of updating the target ConfigMap in place
+	// The name of the latest one is recorded in status.latestConfigMap
+	// +optional
+	Immutable *ImmutableTargets `json:"immutable,omitempty"`
+
+	// RevisionHistory keeps the content of previous syncs, so the target can be pinned back to it
+	// +optional
+	RevisionHistory *RevisionHistory `json:"revisionHistory,omitempty"`
+
+	// PinnedRevision is the name of a revision in status.history to sync instead of the source content
+	// While set, the source is not fetched; clear it to return to the latest source content
+	// +optional
+	PinnedRevision string `json:"pinnedRevision,omitempty"`
+
+	// Template renders the fetched keys as Go templates for each target namespace
+	// +optional
+	Template *TemplateSpec `json:"template,omitempty"`
+
+	// Substitution replaces ${NAME} placeholders in the fetched content with variables
+	// from ConfigMaps and Secrets, after templates are rendered
+	// +optional
+	Substitution *SubstitutionSpec `json:"substitution,omitempty"`
+}
+
+// SourceSpec defines one entry of an ordered list of sources
+type SourceSpec struct {
+	// Name identifies the source in status and conflict reports
+	// Defaults to the lowercased type and the position in the list, e.g. git-0
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Type specifies the type of source to fetch the configuration from
+	// Valid values are: "Git", "File", "ConfigMap", "Secret"
+	// +kubebuilder:validation:Enum=Git;File;ConfigMap;Secret
+	// +kubebuilder:validation:Required
+	Type string `json:"type"`
+
+	// Git source configuration
+	// +optional
+	Git *GitSource `json:"git,omitempty"`
+
+	// File source configuration
+	// +optional
+	File *FileSource `json:"file,omitempty"`
+
+	// ConfigMap source configuration
+	// +optional
+	ConfigMap *ConfigMapSource `json:"configMap,omitempty"`
+
+	// Secret source configuration
+	// +optional
+	Secret *SecretSource `json:"secret,omitempty"`
+}
+
+// RolloutTarget selects workloads to restart when the target ConfigMap content changes
+type RolloutTarget struct {
+	// Kind of the workloads
+	// Valid values are: "Deployment", "StatefulSet", "DaemonSet"
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet;DaemonSet
+	// +kubebuilder:validation:Required
+	Kind string `json:"kind"`
+
+	// Name of the workload
+	// Exactly one of Name and Selector must be set
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Selector selects workloads by label
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Namespace of the workloads, which must be a target namespace
+	// If not specified, workloads are looked up in every target namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ImmutableTargets configures content-addressed target ConfigMaps
+type ImmutableTargets struct {
+	// HistoryLimit is the number of previous ConfigMaps kept besides the latest one
+	// Older ones are deleted once no pod references them
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=3
+	// +optional
+	HistoryLimit *int32 `json:"historyLimit,omitempty"`
+}
+
+// RevisionHistory configures the revisions kept of the synced content
+type RevisionHistory struct {
+	// Limit is the number of revisions kept, including the current one
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10
+	// +optional
+	Limit *int32 `json:"limit,omitempty"`
+
+	// Namespace is where the revisions of a ClusterConfigMapSource are stored
+	// ConfigMapSources always store them in their own namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// TemplateSpec configures Go template rendering of fetched content
+// Templates are rendered with .Values (the keys of the valuesFrom objects), .Namespace (the
+// target namespace) and .Labels (the labels of the source), and a safe subset of the Sprig functions
+type TemplateSpec struct {
+	// Include lists glob patterns of the keys to render
+	// If empty, all keys in data are rendered, binaryData is never rendered
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// ValuesFrom lists ConfigMaps and Secrets whose keys are available to templates as .Values
+	// Later entries take precedence for keys they both provide
+	// +optional
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+}
+
+// SubstitutionSpec configures ${NAME} placeholder substitution in fetched content
+// Names consist of letters, digits and underscores and don't start with a digit,
+// $${NAME} is written as a literal ${NAME}
+type SubstitutionSpec struct {
+	// VariablesFrom lists ConfigMaps and Secrets whose keys are the variables
+	// Later entries take precedence for keys they both provide
+	// +optional
+	VariablesFrom []ValuesReference `json:"variablesFrom,omitempty"`
+
+	// Include lists glob patterns of the keys to substitute variables in
+	// If empty, all keys in data are substituted, binaryData never is
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Strict fails the sync if a placeholder has no variable
+	// Otherwise such placeholders are left as they are
+	// +optional
+	Strict bool `json:"strict,omitempty"`
 }
 
 // GitSource defines Git repository source configuration
@@ -63,6 +272,8 @@ type GitSource struct {
 	// Authentication reference (Secret name)
 	// +optional
 	AuthSecretRef *SecretReference `json:"authSecretRef,omitempty"`
+
+	FileReadOptions `json:",inline"`
 }
 
 // FileSource defines file source configuration
@@ -70,6 +281,62 @@ type FileSource struct {
 	// Path to the file or directory containing the configuration
 	// +kubebuilder:validation:Required
 	Path string `json:"path"`
+
+	FileReadOptions `json:",inline"`
+}
+
+// FileReadOptions configures how files under a File or Git source path are read
+type FileReadOptions struct {
+	// Recursive reads files in subdirectories of the path as well
+	// If not set, subdirectories are skipped
+	// +optional
+	Recursive bool `json:"recursive,omitempty"`
+
+	// KeyMapping controls how file paths relative to the source path are turned into ConfigMap keys
+	// If not specified, paths are flattened with "__" as separator
+	// +optional
+	KeyMapping *KeyMapping `json:"keyMapping,omitempty"`
+
+	// Include lists glob patterns of files to read, matched against paths relative to the source path
+	// "**" matches any number of directories, e.g. "**/*.yaml"
+	// If empty, all files are included
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Exclude lists glob patterns of files to skip, applied after Include
+	// e.g. "**/README.md", "**/.gitkeep" or "**/*~"
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+
+	// BinaryFiles lists glob patterns of files that are always written to the target's binaryData
+	// Other files are written to binaryData only if they are not valid UTF-8
+	// +optional
+	BinaryFiles []string `json:"binaryFiles,omitempty"`
+}
+
+// KeyMapping defines how relative file paths are turned into ConfigMap keys,
+// which cannot contain "/"
+type KeyMapping struct {
+	// Strategy for turning a relative path into a key
+	// Flatten joins the path segments with Separator, e.g. cache/redis.conf becomes cache__redis.conf
+	// Basename uses the file name only and fails if two files share a name
+	// Template renders Template with the path
+	// +kubebuilder:validation:Enum=Flatten;Basename;Template
+	// +kubebuilder:default=Flatten
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+
+	// Separator joins path segments with the Flatten strategy
+	// Defaults to "__"
+	// +optional
+	Separator string `json:"separator,omitempty"`
+
+	// Template is a Go template rendering the key with the Template strategy
+	// Available fields are .Path (relative path), .Dir, .Base, .Name (base without extension) and .Ext,
+	// and the functions replace, trimPrefix, trimSuffix, lower and upper
+	// e.g. {{ .Path | replace "/" "." }}
+	// +optional
+	Template string `json:"template,omitempty"`
 }
 
 // ConfigMapSource defines an existing ConfigMap as a source
@@ -79,6 +346,7 @@ type ConfigMapSource struct {
 	Name string `json:"name"`
 
 	// Namespace of the source ConfigMap
+	// A ConfigMapSource can only name its own namespace
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
 
@@ -94,6 +362,7 @@ type SecretSource struct {
 	Name string `json:"name"`
 
 	// Namespace of the source Secret
+	// A ConfigMapSource can only name its own namespace
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
 
@@ -109,13 +378,53 @@ type SecretReference struct {
 	Name string `json:"name"`
 
 	// Namespace of the Secret
+	// A ConfigMapSource can only name its own namespace
 	// +optional
 	Namespace string `json:"namespace,omitempty"`
 
 	// Key within the Secret containing the authentication information
 	// For Git, this could be a SSH key or a username:password for HTTPS
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// UsernameKey is the key within the Secret containing the username for HTTPS basic auth
+	// Must be set together with PasswordKey
+	// +optional
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// PasswordKey is the key within the Secret containing the password for HTTPS basic auth
+	// Must be set together with UsernameKey
+	// +optional
+	PasswordKey string `json:"passwordKey,omitempty"`
+
+	// TokenKey is the key within the Secret containing a bearer token for HTTPS
+	// +optional
+	TokenKey string `json:"tokenKey,omitempty"`
+}
+
+// ValuesReference references a ConfigMap or Secret whose keys are used as values
+// It has the name and namespace of SecretReference, which only points at Secrets and whose
+// keys select Git credentials, plus the kind of object and whether it may be missing
+type ValuesReference struct {
+	// Kind of the object
+	// Valid values are: "ConfigMap", "Secret"
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
 	// +kubebuilder:validation:Required
-	Key string `json:"key"`
+	Kind string `json:"kind"`
+
+	// Name of the object
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Namespace of the object
+	// If not specified, the same namespace as the ConfigMapSource will be used
+	// A ConfigMapSource can only name its own namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Optional skips the object if it doesn't exist
+	// +optional
+	Optional bool `json:"optional,omitempty"`
 }
 
 // ConfigMapSourceStatus defines the observed state of ConfigMapSource
@@ -129,6 +438,49 @@ type ConfigMapSourceStatus struct {
 	// +optional
 	LastSyncHash string `json:"lastSyncHash,omitempty"`
 
+	// SourceRevision identifies the revision of the source that was last synced
+	// +optional
+	SourceRevision *SourceRevision `json:"sourceRevision,omitempty"`
+
+	// GitPoll records the last checks of the Git remote for new commits
+	// +optional
+	GitPoll *GitPollStatus `json:"gitPoll,omitempty"`
+
+	// Sources records the state of each entry of spec.sources
+	// +optional
+	Sources []SourceStatus `json:"sources,omitempty"`
+
+	// KeyConflicts lists the keys provided by more than one entry of spec.sources
+	// +optional
+	KeyConflicts []KeyConflict `json:"keyConflicts,omitempty"`
+
+	// Targets records the sync result of the target ConfigMap in each target namespace
+	// +optional
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// Rollout records the workloads restarted for the synced content
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// LatestConfigMap is the name of the immutable ConfigMap holding the synced content,
+	// if spec.immutable is set
+	// +optional
+	LatestConfigMap string `json:"latestConfigMap,omitempty"`
+
+	// History lists the revisions kept of the synced content, newest first
+	// +optional
+	History []RevisionStatus `json:"history,omitempty"`
+
+	// KeyDigests maps each synced key to the sha256 digest of its value as written to the targets,
+	// after templates and variables are applied
+	// A key rendered differently per target namespace has a digest covering every namespace
+	// +optional
+	KeyDigests map[string]string `json:"keyDigests,omitempty"`
+
+	// LastSyncChanges lists the keys that were added, removed or changed by the last sync
+	// +optional
+	LastSyncChanges *KeyChanges `json:"lastSyncChanges,omitempty"`
+
 	// Conditions represents the latest available observations of the ConfigMapSource's state
 	// +optional
 	// +patchMergeKey=type
@@ -136,11 +488,151 @@ type ConfigMapSourceStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
+// SourceRevision identifies a specific revision of a configuration source
+type SourceRevision struct {
+	// Ref is the Git reference the revision was resolved from, e.g. refs/heads/main or refs/tags/v1.2.0
+	// Empty when the revision is a commit SHA
+	// +optional
+	Ref string `json:"ref,omitempty"`
+
+	// Commit is the Git commit SHA the revision resolved to
+	// +optional
+	Commit string `json:"commit,omitempty"`
+
+	// ResourceVersion is the resourceVersion of the source ConfigMap or Secret
+	// +optional
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+
+	// ModTime is the most recent modification time of the files read from a File source
+	// +optional
+	ModTime *metav1.Time `json:"modTime,omitempty"`
+}
+
+// SourceStatus is the observed state of one entry of spec.sources
+type SourceStatus struct {
+	// Name of the source
+	Name string `json:"name"`
+
+	// Revision identifies the revision of the source that was last synced
+	// +optional
+	Revision *SourceRevision `json:"revision,omitempty"`
+
+	// GitPoll records the last checks of the Git remote for new commits
+	// +optional
+	GitPoll *GitPollStatus `json:"gitPoll,omitempty"`
+}
+
+// TargetStatus is the sync result of the target ConfigMap in one namespace
+type TargetStatus struct {
+	// Namespace of the target ConfigMap
+	Namespace string `json:"namespace"`
+
+	// Synced is true if the target ConfigMap holds the source content
+	Synced bool `json:"synced"`
+
+	// Drifted is true if the target ConfigMap no longer matches the source content and was left alone
+	// +optional
+	Drifted bool `json:"drifted,omitempty"`
+
+	// LastSyncTime is when the source content was last written to the target ConfigMap
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Message describes why the last sync of the target ConfigMap failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RolloutStatus records the workloads restarted for a content change
+type RolloutStatus struct {
+	// Hash is the LastSyncHash the workloads were restarted for
+	Hash string `json:"hash"`
+
+	// Time is when the workloads were restarted
+	// +optional
+	Time *metav1.Time `json:"time,omitempty"`
+
+	// Workloads lists the workloads whose pod template carries the hash
+	// +optional
+	Workloads []WorkloadReference `json:"workloads,omitempty"`
+}
+
+// RevisionStatus describes a revision of the synced content
+type RevisionStatus struct {
+	// Name of the revision, the first characters of its hash
+	Name string `json:"name"`
+
+	// Hash of the fetched content, before templates are rendered
+	Hash string `json:"hash"`
+
+	// SourceRevision identifies the revision of the source the content was fetched from
+	// +optional
+	SourceRevision *SourceRevision `json:"sourceRevision,omitempty"`
+
+	// SyncTime is when the content was last synced
+	// +optional
+	SyncTime *metav1.Time `json:"syncTime,omitempty"`
+}
+
+// WorkloadReference identifies a workload
+type WorkloadReference struct {
+	// Kind of the workload
+	Kind string `json:"kind"`
+
+	// Namespace of the workload
+	Namespace string `json:"namespace"`
+
+	// Name of the workload
+	Name string `json:"name"`
+}
+
+// KeyConflict is a key provided by more than one source
+type KeyConflict struct {
+	// Key is the conflicting key
+	Key string `json:"key"`
+
+	// Sources are the names of the sources providing the key, in order
+	// The value of the last one is written to the target
+	Sources []string `json:"sources"`
+}
+
+// GitPollStatus records checks of a Git remote for new commits
+type GitPollStatus struct {
+	// LastPollTime is when the remote refs were last listed
+	// +optional
+	LastPollTime *metav1.Time `json:"lastPollTime,omitempty"`
+
+	// LastFetchTime is when objects were last fetched from the remote
+	// Polls that find the ref still at the last synced commit skip the fetch
+	// +optional
+	LastFetchTime *metav1.Time `json:"lastFetchTime,omitempty"`
+
+	// Commit is the commit the revision pointed at when the remote was last polled
+	// +optional
+	Commit string `json:"commit,omitempty"`
+}
+
+// KeyChanges lists the keys that differ between two syncs
+type KeyChanges struct {
+	// Added are keys that were not present in the previous sync
+	// +optional
+	Added []string `json:"added,omitempty"`
+
+	// Removed are keys that are no longer present
+	// +optional
+	Removed []string `json:"removed,omitempty"`
+
+	// Changed are keys whose value changed
+	// +optional
+	Changed []string `json:"changed,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Source Type",type="string",JSONPath=".spec.sourceType"
 // +kubebuilder:printcolumn:name="Target ConfigMap",type="string",JSONPath=".spec.targetConfigMap"
 // +kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime"
+// +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.sourceRevision.commit",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ConfigMapSource is the Schema for the configmapsources API
@@ -152,6 +644,16 @@ type ConfigMapSource struct {
 	Status ConfigMapSourceStatus `json:"status,omitempty"`
 }
 
+// GetSpec returns the spec of the ConfigMapSource
+func (c *ConfigMapSource) GetSpec() *ConfigMapSourceSpec {
+	return &c.Spec
+}
+
+// GetStatus returns the status of the ConfigMapSource
+func (c *ConfigMapSource) GetStatus() *ConfigMapSourceStatus {
+	return &c.Status
+}
+
 // +kubebuilder:object:root=true
 
 // ConfigMapSourceList contains a list of ConfigMapSource
@@ -3,38 +3,67 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// gitPinnedRefPrefix prefixes the mirror refs that keep commits fetched by SHA
+const gitPinnedRefPrefix = "refs/pinned/"
+
+const (
+	// sourceConfigMapIndex indexes ConfigMapSources by the namespace/name of their source ConfigMap
+	sourceConfigMapIndex = ".spec.configMap.namespacedName"
+	// sourceSecretIndex indexes ConfigMapSources by the namespace/name of their source Secret
+	sourceSecretIndex = ".spec.secret.namespacedName"
+)
+
 // ConfigMapSourceReconciler reconciles a ConfigMapSource object
 type ConfigMapSourceReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
-	Log    logr.Logger
+	Scheme   *runtime.Scheme
+	Log      logr.Logger
+	Recorder record.EventRecorder
+
+	// GitCache holds the Git mirrors shared by all Git sources
+	// If not set, SetupWithManager uses one in the system temp directory shared by all reconcilers
+	GitCache *GitCache
 }
 
 // +kubebuilder:rbac:groups=config.example.com,resources=configmapsources,verbs=get;list;watch;create;update;patch;delete
@@ -42,6 +71,11 @@ type ConfigMapSourceReconciler struct {
 // +kubebuilder:rbac:groups=config.example.com,resources=configmapsources/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
 
 // Reconcile handles ConfigMapSource resources
 func (r *ConfigMapSourceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -61,149 +95,436 @@ func (r *ConfigMapSourceReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, err
 	}
 
+	return r.reconcileSource(ctx, &configMapSource)
+}
+
+// reconcileSource syncs a ConfigMapSource or ClusterConfigMapSource to its targets
+func (r *ConfigMapSourceReconciler) reconcileSource(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
 	// Add finalizer to handle cleanup when the resource is deleted
-	if !controllerutil.ContainsFinalizer(&configMapSource, "configmapsource.config.example.com/finalizer") {
-		controllerutil.AddFinalizer(&configMapSource, "configmapsource.config.example.com/finalizer")
-		if err := r.Update(ctx, &configMapSource); err != nil {
+	if !controllerutil.ContainsFinalizer(configMapSource, "configmapsource.config.example.com/finalizer") {
+		controllerutil.AddFinalizer(configMapSource, "configmapsource.config.example.com/finalizer")
+		if err := r.Update(ctx, configMapSource); err != nil {
 			logger.Error(err, "Failed to add finalizer")
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil // Requeue to continue processing
+		// Adding the finalizer doesn't change the generation, so the update event is filtered out
+		return ctrl.Result{Requeue: true}, nil
 	}
 
 	// Handle resource deletion
-	if !configMapSource.DeletionTimestamp.IsZero() {
-		return r.reconcileDelete(ctx, &configMapSource)
+	if !configMapSource.GetDeletionTimestamp().IsZero() {
+		return r.reconcileDelete(ctx, configMapSource)
 	}
 
-	// Set target namespace if not specified
-	targetNamespace := configMapSource.Spec.TargetNamespace
-	if targetNamespace == "" {
-		targetNamespace = configMapSource.Namespace
+	syncAttempts.WithLabelValues(sourceTypeLabel(configMapSource)).Inc()
+
+	// A namespaced source may only read and write in its own namespace
+	if err := checkNamespaces(configMapSource); err != nil {
+		logger.Error(err, "Source reaches outside its namespace")
+		r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, errorReason(err, "NamespaceNotAllowed"), "Source reaches outside its namespace: %v", err)
+		r.updateFailedStatus(ctx, configMapSource, errorReason(err, "NamespaceNotAllowed"), fmt.Sprintf("Source reaches outside its namespace: %v", err))
+		return ctrl.Result{}, err
 	}
 
 	// Fetch configuration data from the source
-	configData, err := r.fetchConfigData(ctx, &configMapSource)
+	fetched, err := r.fetchConfigData(ctx, configMapSource)
 	if err != nil {
 		// Update status condition to reflect failure
-		r.setStatusCondition(&configMapSource, metav1.Condition{
+		r.setStatusCondition(configMapSource, metav1.Condition{
 			Type:    "Ready",
 			Status:  metav1.ConditionFalse,
-			Reason:  "FetchFailed",
+			Reason:  errorReason(err, "FetchFailed"),
 			Message: fmt.Sprintf("Failed to fetch configuration data: %v", err),
 		})
-		if updateErr := r.Status().Update(ctx, &configMapSource); updateErr != nil {
+		if updateErr := r.Status().Update(ctx, configMapSource); updateErr != nil {
 			logger.Error(updateErr, "Failed to update ConfigMapSource status after fetch failure")
+		} else {
+			sourceStates.observe(configMapSource)
 		}
 		logger.Error(err, "Failed to fetch configuration data")
+		syncFailures.WithLabelValues(sourceTypeLabel(configMapSource), errorReason(err, "FetchFailed")).Inc()
+		r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, errorReason(err, "FetchFailed"), "Failed to fetch configuration data: %v", err)
 		return ctrl.Result{RequeueAfter: time.Minute}, err // Retry after a minute
 	}
 
+	configData := fetched.data
+	binaryData := fetched.binaryData
+
+	// Load the values the templates are rendered with
+	renderer, err := r.newTemplateRenderer(ctx, configMapSource)
+	if err != nil {
+		logger.Error(err, "Failed to load template values")
+		r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, errorReason(err, "RenderFailed"), "Failed to load template values: %v", err)
+		r.updateFailedStatus(ctx, configMapSource, errorReason(err, "RenderFailed"), fmt.Sprintf("Failed to load template values: %v", err))
+		return ctrl.Result{}, err
+	}
+
+	// Load the variables substituted into the rendered content
+	substituter, err := r.newSubstituter(ctx, configMapSource)
+	if err != nil {
+		logger.Error(err, "Failed to load variables")
+		r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, errorReason(err, "SubstitutionFailed"), "Failed to load variables: %v", err)
+		r.updateFailedStatus(ctx, configMapSource, errorReason(err, "SubstitutionFailed"), fmt.Sprintf("Failed to load variables: %v", err))
+		return ctrl.Result{}, err
+	}
+
 	// Calculate hash of the config data for change detection
-	configHash := calculateConfigHash(configData)
+	// Templates and variables change the synced content without changing the fetched content,
+	// so their inputs are hashed too
+	contentHash := calculateConfigHash(configData, binaryData)
+	configHash := contentHash
+	if renderer != nil {
+		configHash = renderer.hash(configHash)
+	}
+	if substituter != nil {
+		configHash = substituter.hash(configHash)
+	}
+
+	// Resolve the namespaces the target ConfigMap is synced to
+	targetNamespaces, err := r.targetNamespaces(ctx, configMapSource)
+	if err != nil {
+		logger.Error(err, "Failed to resolve target namespaces")
+		r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, errorReason(err, "NamespaceResolutionFailed"), "Failed to resolve target namespaces: %v", err)
+		r.updateFailedStatus(ctx, configMapSource, errorReason(err, "NamespaceResolutionFailed"), fmt.Sprintf("Failed to resolve target namespaces: %v", err))
+		return ctrl.Result{}, err
+	}
+
+	// The content this source wants in the target
+	desired := &desiredTarget{
+		strategy:    configMapSource.GetSpec().MergeStrategy,
+		data:        configData,
+		binaryData:  binaryData,
+		hash:        configHash,
+		renderer:    renderer,
+		substituter: substituter,
+	}
 
 	// Check if the configuration has changed
-	if configHash == configMapSource.Status.LastSyncHash {
-		logger.Info("Configuration unchanged, no update needed")
-		// Still update the status to reflect successful sync attempt
-		now := metav1.Now()
-		configMapSource.Status.LastSyncTime = &now
-		if err := r.Status().Update(ctx, &configMapSource); err != nil {
-			logger.Error(err, "Failed to update ConfigMapSource status for unchanged configuration")
-			return ctrl.Result{}, err
+	configChanged := configHash != configMapSource.GetStatus().LastSyncHash
+	driftPolicy := configMapSource.GetSpec().DriftPolicy
+	if driftPolicy == "" {
+		driftPolicy = "Correct"
+	}
+
+	previousTargets := make(map[string]configv1alpha1.TargetStatus)
+	for _, target := range configMapSource.GetStatus().Targets {
+		previousTargets[target.Namespace] = target
+	}
+
+	// Sync the target ConfigMap in every target namespace
+	// Namespaces that were not synced before, like newly labelled ones, are always written,
+	// drift left alone by the drift policy is not
+	now := metav1.Now()
+	targets := make([]configv1alpha1.TargetStatus, 0, len(targetNamespaces))
+	var drifted, corrected []string
+	replaced := make(map[string]bool)
+	rendered := make(map[string]*desiredTarget)
+	var syncErr error
+	failed := 0
+	conflict := false
+	var targetConflicts []string
+	renderErrors := make(map[string]bool)
+	for _, namespace := range targetNamespaces {
+		previous, synced := previousTargets[namespace]
+		sync := configChanged || !synced || (!previous.Synced && !previous.Drifted)
+		delete(previousTargets, namespace)
+
+		target := configv1alpha1.TargetStatus{
+			Namespace:    namespace,
+			LastSyncTime: previous.LastSyncTime,
+		}
+		result, err := r.syncTarget(ctx, configMapSource, namespace, desired, sync, driftPolicy)
+		if result.content != nil {
+			rendered[namespace] = result.content
+		}
+		if result.drifted && result.applied {
+			corrected = append(corrected, namespace)
+		} else if result.drifted {
+			drifted = append(drifted, namespace)
+			target.Drifted = true
 		}
+		if err != nil {
+			logger.Error(err, "Failed to sync target ConfigMap", "namespace", namespace)
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, errorReason(err, "ApplyFailed"), "Failed to sync target ConfigMap in namespace %s: %v", namespace, err)
+			target.Message = err.Error()
+			conflict = conflict || errorReason(err, "") == "Conflict"
+			if errorReason(err, "") == "TargetConflict" {
+				targetConflicts = append(targetConflicts, err.Error())
+			}
+			if errorReason(err, "") == "RenderFailed" {
+				renderErrors[err.Error()] = true
+			}
+			failed++
+			if syncErr == nil {
+				syncErr = err
+			}
+		} else if target.Drifted {
+			target.Message = "Target ConfigMap no longer matches the source"
+		} else {
+			target.Synced = true
+			if result.applied {
+				target.LastSyncTime = &now
+			}
+			if result.replaced {
+				replaced[namespace] = true
+			}
+		}
+		targets = append(targets, target)
+	}
 
-		// Requeue based on refresh interval
-		return r.requeueBasedOnRefreshInterval(&configMapSource)
+	// Clean up namespaces that are no longer targeted
+	for namespace, previous := range previousTargets {
+		if err := r.removeTarget(ctx, configMapSource, namespace); err != nil {
+			logger.Error(err, "Failed to clean up target ConfigMap", "namespace", namespace)
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, "CleanupFailed", "Failed to clean up target ConfigMap in namespace %s: %v", namespace, err)
+			// Keep the namespace in status so the cleanup is retried
+			previous.Synced = false
+			previous.Message = fmt.Sprintf("Failed to clean up target ConfigMap: %v", err)
+			targets = append(targets, previous)
+			failed++
+			if syncErr == nil {
+				syncErr = err
+			}
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Namespace < targets[j].Namespace
+	})
+	configMapSource.GetStatus().Targets = targets
+
+	// The Drifted condition reflects the targets checked in this reconcile
+	switch {
+	case len(drifted) > 0:
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "Drifted",
+			Status:  metav1.ConditionTrue,
+			Reason:  "DriftDetected",
+			Message: fmt.Sprintf("Target ConfigMap %s no longer matches the source in namespaces %s", configMapSource.GetSpec().TargetConfigMap, strings.Join(drifted, ", ")),
+		})
+	case len(corrected) > 0:
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "Drifted",
+			Status:  metav1.ConditionFalse,
+			Reason:  "DriftCorrected",
+			Message: fmt.Sprintf("Restored target ConfigMap %s to the source content in namespaces %s", configMapSource.GetSpec().TargetConfigMap, strings.Join(corrected, ", ")),
+		})
+	case driftPolicy == "Ignore":
+		if meta.FindStatusCondition(configMapSource.GetStatus().Conditions, "Drifted") != nil {
+			r.setStatusCondition(configMapSource, metav1.Condition{
+				Type:    "Drifted",
+				Status:  metav1.ConditionFalse,
+				Reason:  "DriftIgnored",
+				Message: "Drift detection is disabled",
+			})
+		}
+	default:
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "Drifted",
+			Status:  metav1.ConditionFalse,
+			Reason:  "InSync",
+			Message: "Target ConfigMap matches the source",
+		})
 	}
 
-	// Get the target ConfigMap if it exists
-	var targetConfigMap corev1.ConfigMap
-	targetConfigMapName := types.NamespacedName{
-		Name:      configMapSource.Spec.TargetConfigMap,
-		Namespace: targetNamespace,
+	// Existing targets the source may not write to are named with their current owner
+	if len(targetConflicts) > 0 {
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "TargetConflict",
+			Status:  metav1.ConditionTrue,
+			Reason:  "TargetNotOwned",
+			Message: strings.Join(targetConflicts, "; "),
+		})
+	} else if meta.FindStatusCondition(configMapSource.GetStatus().Conditions, "TargetConflict") != nil {
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "TargetConflict",
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoConflict",
+			Message: "No target ConfigMap is owned by someone else",
+		})
 	}
-	configMapExists := true
-	if err := r.Get(ctx, targetConfigMapName, &targetConfigMap); err != nil {
-		if !apierrors.IsNotFound(err) {
-			logger.Error(err, "Failed to get target ConfigMap")
-			return ctrl.Result{}, err
+
+	// Templates mostly fail the same way in every namespace, so each error is reported once
+	if len(renderErrors) > 0 {
+		messages := make([]string, 0, len(renderErrors))
+		for message := range renderErrors {
+			messages = append(messages, message)
 		}
-		configMapExists = false
+		sort.Strings(messages)
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "RenderFailed",
+			Status:  metav1.ConditionTrue,
+			Reason:  "TemplateError",
+			Message: strings.Join(messages, "; "),
+		})
+	} else if meta.FindStatusCondition(configMapSource.GetStatus().Conditions, "RenderFailed") != nil {
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "RenderFailed",
+			Status:  metav1.ConditionFalse,
+			Reason:  "Rendered",
+			Message: "All templates rendered",
+		})
+	}
 
-		// Initialize new ConfigMap if it doesn't exist
-		targetConfigMap = corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      configMapSource.Spec.TargetConfigMap,
-				Namespace: targetNamespace,
-			},
-			Data: make(map[string]string),
+	if syncErr != nil {
+		if conflict {
+			r.setStatusCondition(configMapSource, metav1.Condition{
+				Type:    "Conflict",
+				Status:  metav1.ConditionTrue,
+				Reason:  "FieldManagerConflict",
+				Message: "Keys of the target ConfigMap are owned by another field manager, see status.targets",
+			})
 		}
+		r.updateFailedStatus(ctx, configMapSource, errorReason(syncErr, "ApplyFailed"), fmt.Sprintf("Failed to sync target ConfigMap in %d of %d namespaces: %v", failed, len(targets), syncErr))
+		return ctrl.Result{}, syncErr
 	}
 
-	// Update or create the target ConfigMap
-	if configMapExists {
-		logger.Info("Updating existing ConfigMap", "name", targetConfigMapName)
-		targetConfigMap.Data = configData
-		if err := r.Update(ctx, &targetConfigMap); err != nil {
-			logger.Error(err, "Failed to update ConfigMap")
+	// Restart the workloads consuming the targets whose content was replaced in this reconcile,
+	// for changed content, corrected drift or adopted and overwritten ConfigMaps. Workloads can't
+	// have started with a target created by this reconcile, so it doesn't restart them.
+	// On failure the hash is not recorded, so the next reconcile retries a rollout for changed content
+	if len(configMapSource.GetSpec().RolloutTargets) == 0 {
+		configMapSource.GetStatus().Rollout = nil
+	} else if len(replaced) > 0 {
+		// Workloads carrying the hash already are restarted anyway if the content didn't change
+		restartedAt := ""
+		if !configChanged {
+			restartedAt = now.UTC().Format(time.RFC3339)
+		}
+		workloads, err := r.rolloutWorkloads(ctx, configMapSource, targetNamespaces, replaced, configHash, restartedAt)
+		if err != nil {
+			logger.Error(err, "Failed to restart rollout targets")
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, errorReason(err, "RolloutFailed"), "Failed to restart rollout targets: %v", err)
+			r.updateFailedStatus(ctx, configMapSource, errorReason(err, "RolloutFailed"), fmt.Sprintf("Failed to restart rollout targets: %v", err))
 			return ctrl.Result{}, err
 		}
-	} else {
-		logger.Info("Creating new ConfigMap", "name", targetConfigMapName)
-		targetConfigMap.Data = configData
-
-		// Set owner reference if in the same namespace
-		if configMapSource.Namespace == targetNamespace {
-			if err := controllerutil.SetControllerReference(&configMapSource, &targetConfigMap, r.Scheme); err != nil {
-				logger.Error(err, "Failed to set owner reference on ConfigMap")
-				return ctrl.Result{}, err
-			}
+		configMapSource.GetStatus().Rollout = &configv1alpha1.RolloutStatus{
+			Hash:      configHash,
+			Time:      &now,
+			Workloads: workloads,
 		}
+	}
 
-		if err := r.Create(ctx, &targetConfigMap); err != nil {
-			logger.Error(err, "Failed to create ConfigMap")
+	// Keep the synced content for rollback, unless it is the newest revision already
+	// Revisions hold the fetched content, so they are keyed by its hash, and a pinned
+	// revision is rendered with the current template values and variables
+	history := configMapSource.GetStatus().History
+	if configMapSource.GetSpec().RevisionHistory != nil && (len(history) == 0 || history[0].Hash != contentHash) {
+		if err := r.recordRevision(ctx, configMapSource, fetched, contentHash, now); err != nil {
+			logger.Error(err, "Failed to record revision")
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, errorReason(err, "HistoryFailed"), "Failed to record revision: %v", err)
+			r.updateFailedStatus(ctx, configMapSource, errorReason(err, "HistoryFailed"), fmt.Sprintf("Failed to record revision: %v", err))
 			return ctrl.Result{}, err
 		}
 	}
+	if pinned := configMapSource.GetSpec().PinnedRevision; pinned != "" {
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "Pinned",
+			Status:  metav1.ConditionTrue,
+			Reason:  "RevisionPinned",
+			Message: fmt.Sprintf("Target ConfigMap is pinned to revision %s, source changes are not synced", pinned),
+		})
+	} else if meta.FindStatusCondition(configMapSource.GetStatus().Conditions, "Pinned") != nil {
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "Pinned",
+			Status:  metav1.ConditionFalse,
+			Reason:  "FollowingSource",
+			Message: "Target ConfigMap follows the source",
+		})
+	}
 
 	// Update status with sync info
-	now := metav1.Now()
-	configMapSource.Status.LastSyncTime = &now
-	configMapSource.Status.LastSyncHash = configHash
-	r.setStatusCondition(&configMapSource, metav1.Condition{
+	configMapSource.GetStatus().LastSyncTime = &now
+	configMapSource.GetStatus().LastSyncHash = configHash
+	configMapSource.GetStatus().SourceRevision = fetched.revision
+	configMapSource.GetStatus().Sources = fetched.sources
+	configMapSource.GetStatus().KeyConflicts = fetched.conflicts
+	configMapSource.GetStatus().LatestConfigMap = ""
+	if configMapSource.GetSpec().Immutable != nil {
+		configMapSource.GetStatus().LatestConfigMap = immutableTargetName(configMapSource, configHash)
+	}
+	// Digests describe the content written to the targets, so changes to template values
+	// or variables show up as changed keys
+	keyDigests := calculateKeyDigests(configData, binaryData)
+	if renderer != nil || substituter != nil {
+		keyDigests = calculateRenderedKeyDigests(rendered)
+	}
+	if configChanged {
+		configMapSource.GetStatus().LastSyncChanges = diffKeyDigests(configMapSource.GetStatus().KeyDigests, keyDigests)
+	}
+	configMapSource.GetStatus().KeyDigests = keyDigests
+	if meta.FindStatusCondition(configMapSource.GetStatus().Conditions, "Conflict") != nil {
+		r.setStatusCondition(configMapSource, metav1.Condition{
+			Type:    "Conflict",
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoConflict",
+			Message: "All source keys were applied to the target ConfigMap",
+		})
+	}
+	r.setStatusCondition(configMapSource, metav1.Condition{
 		Type:    "Ready",
 		Status:  metav1.ConditionTrue,
 		Reason:  "SyncSuccess",
 		Message: "Successfully synced configuration data",
 	})
-	if err := r.Status().Update(ctx, &configMapSource); err != nil {
+	if err := r.Status().Update(ctx, configMapSource); err != nil {
 		logger.Error(err, "Failed to update ConfigMapSource status")
+		syncFailures.WithLabelValues(sourceTypeLabel(configMapSource), "StatusUpdateFailed").Inc()
 		return ctrl.Result{}, err
 	}
+	syncSuccesses.WithLabelValues(sourceTypeLabel(configMapSource)).Inc()
+	sourceStates.observe(configMapSource)
 
-	logger.Info("Successfully reconciled ConfigMapSource", "name", req.NamespacedName)
+	logger.Info("Successfully reconciled ConfigMapSource", "name", client.ObjectKeyFromObject(configMapSource), "namespaces", len(targetNamespaces))
 
 	// Requeue based on refresh interval
-	return r.requeueBasedOnRefreshInterval(&configMapSource)
+	return r.requeueBasedOnRefreshInterval(configMapSource)
 }
 
 // reconcileDelete handles the deletion of a ConfigMapSource resource
-func (r *ConfigMapSourceReconciler) reconcileDelete(ctx context.Context, configMapSource *configv1alpha1.ConfigMapSource) (ctrl.Result, error) {
+func (r *ConfigMapSourceReconciler) reconcileDelete(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
-	logger.Info("Deleting ConfigMapSource", "name", configMapSource.Name)
+	logger.Info("Deleting ConfigMapSource", "name", configMapSource.GetName())
+
+	// Determine the target namespaces, including those synced before
+	targetNamespaces := []string{configMapSource.GetSpec().TargetNamespace}
+	if configMapSource.GetSpec().TargetNamespace == "" {
+		targetNamespaces[0] = configMapSource.GetNamespace()
+	}
+	targetNamespaces = append(targetNamespaces, configMapSource.GetSpec().TargetNamespaces...)
+	for _, target := range configMapSource.GetStatus().Targets {
+		targetNamespaces = append(targetNamespaces, target.Namespace)
+	}
 
-	// Determine the target namespace
-	targetNamespace := configMapSource.Spec.TargetNamespace
-	if targetNamespace == "" {
-		targetNamespace = configMapSource.Namespace
+	// Targets tracked by label may be in namespaces missing from the spec and status
+	var labelledConfigMaps corev1.ConfigMapList
+	if err := r.List(ctx, &labelledConfigMaps, client.MatchingLabels{ownerUIDLabel: string(configMapSource.GetUID())}); err != nil {
+		logger.Error(err, "Failed to list labelled target ConfigMaps during deletion")
+		return ctrl.Result{}, err
+	}
+	for _, configMap := range labelledConfigMaps.Items {
+		targetNamespaces = append(targetNamespaces, configMap.Namespace)
 	}
 
-	// Remove the target ConfigMap if we own it
-	if configMapSource.Namespace == targetNamespace {
+	// Remove the target ConfigMaps we own, and our keys from the others
+	released := make(map[string]bool)
+	for _, targetNamespace := range targetNamespaces {
+		if targetNamespace == "" || released[targetNamespace] {
+			continue
+		}
+		released[targetNamespace] = true
+
+		// Immutable targets are found by name and ownership, whether spec.immutable is set
+		// or they are left over from before it was unset
+		if err := r.releaseImmutableTargets(ctx, configMapSource, targetNamespace); err != nil {
+			logger.Error(err, "Failed to release immutable ConfigMaps", "namespace", targetNamespace)
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, "CleanupFailed", "Failed to clean up immutable ConfigMaps in namespace %s: %v", targetNamespace, err)
+			return ctrl.Result{}, err
+		}
 		var targetConfigMap corev1.ConfigMap
 		targetConfigMapName := types.NamespacedName{
-			Name:      configMapSource.Spec.TargetConfigMap,
+			Name:      configMapSource.GetSpec().TargetConfigMap,
 			Namespace: targetNamespace,
 		}
 
@@ -213,147 +534,490 @@ func (r *ConfigMapSourceReconciler) reconcileDelete(ctx context.Context, configM
 				return ctrl.Result{}, err
 			}
 			// ConfigMap already deleted, continue with finalizer removal
-		} else {
-			// Check if this ConfigMapSource is the owner
-			if isOwnedBy(&targetConfigMap, configMapSource) {
-				logger.Info("Deleting owned ConfigMap", "name", targetConfigMapName)
-				if err := r.Delete(ctx, &targetConfigMap); err != nil {
-					if !apierrors.IsNotFound(err) {
-						logger.Error(err, "Failed to delete ConfigMap")
-						return ctrl.Result{}, err
-					}
-				}
-			}
+			continue
+		}
+
+		// Release the target if this ConfigMapSource is the owner, otherwise withdraw the keys it merged in
+		release := r.withdrawKeys
+		if isOwnedBy(&targetConfigMap, configMapSource) {
+			release = r.releaseTarget
+		}
+		if err := release(ctx, configMapSource, &targetConfigMap); err != nil {
+			logger.Error(err, "Failed to release target ConfigMap", "name", targetConfigMapName)
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, "CleanupFailed", "Failed to clean up target ConfigMap %s: %v", targetConfigMapName, err)
+			return ctrl.Result{}, err
 		}
 	}
 
 	// Remove finalizer to allow deletion
+	r.recorder().Event(configMapSource, corev1.EventTypeNormal, "Finalized", "Cleaned up target ConfigMaps, removing finalizer")
 	controllerutil.RemoveFinalizer(configMapSource, "configmapsource.config.example.com/finalizer")
 	if err := r.Update(ctx, configMapSource); err != nil {
 		logger.Error(err, "Failed to remove finalizer")
 		return ctrl.Result{}, err
 	}
+	sourceStates.forget(configMapSource)
 
-	logger.Info("Successfully finalized ConfigMapSource", "name", configMapSource.Name)
+	logger.Info("Successfully finalized ConfigMapSource", "name", configMapSource.GetName())
 	return ctrl.Result{}, nil
 }
 
-// fetchConfigData retrieves configuration data from the specified source
-func (r *ConfigMapSourceReconciler) fetchConfigData(ctx context.Context, configMapSource *configv1alpha1.ConfigMapSource) (map[string]string, error) {
-	switch configMapSource.Spec.SourceType {
+// sourceData holds configuration data fetched from a source
+type sourceData struct {
+	data       map[string]string
+	binaryData map[string][]byte
+	// revision identifies the fetched revision, nil for sources without one
+	revision *configv1alpha1.SourceRevision
+	// sources and conflicts describe the entries of spec.sources, if used
+	sources   []configv1alpha1.SourceStatus
+	conflicts []configv1alpha1.KeyConflict
+}
+
+// set stores a value under key, in binaryData if it is binary or not valid UTF-8
+func (d *sourceData) set(key string, value []byte, binary bool) {
+	if binary || !utf8.Valid(value) {
+		d.binaryData[key] = value
+		return
+	}
+	d.data[key] = string(value)
+}
+
+// fetchConfigData retrieves configuration data from the sources of the ConfigMapSource
+func (r *ConfigMapSourceReconciler) fetchConfigData(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource) (*sourceData, error) {
+	// A pinned revision is read from the history instead of the sources
+	// The sources aren't polled while pinned, so their last state is kept, letting unchanged
+	// Git sources skip the fetch once the pin is lifted
+	if configMapSource.GetSpec().PinnedRevision != "" {
+		fetched, err := r.loadRevision(ctx, configMapSource, configMapSource.GetSpec().PinnedRevision)
+		if err != nil {
+			return nil, err
+		}
+		fetched.sources = configMapSource.GetStatus().Sources
+		return fetched, nil
+	}
+
+	if len(configMapSource.GetSpec().Sources) > 0 {
+		if configMapSource.GetSpec().SourceType != "" {
+			return nil, invalidSources("sourceType and sources cannot both be set")
+		}
+		return r.fetchSources(ctx, configMapSource)
+	}
+	if configMapSource.GetSpec().SourceType == "" {
+		return nil, invalidSources("either sourceType or sources must be set")
+	}
+
+	// The single source keeps its state in the top-level status fields
+	status := &configv1alpha1.SourceStatus{
+		Revision: configMapSource.GetStatus().SourceRevision,
+		GitPoll:  configMapSource.GetStatus().GitPoll,
+	}
+	fetched, err := r.fetchSource(ctx, configMapSource, &specSources(configMapSource)[0], status)
+	configMapSource.GetStatus().GitPoll = status.GitPoll
+	return fetched, err
+}
+
+// fetchSource retrieves configuration data from a single source
+// status holds the previous state of the source and receives its poll results
+func (r *ConfigMapSourceReconciler) fetchSource(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, source *configv1alpha1.SourceSpec, status *configv1alpha1.SourceStatus) (*sourceData, error) {
+	defer observeFetchDuration(source.Type, time.Now())
+
+	switch source.Type {
 	case "Git":
-		return r.fetchFromGit(ctx, configMapSource)
+		return r.fetchFromGit(ctx, configMapSource, source.Git, status)
 	case "File":
-		return r.fetchFromFile(ctx, configMapSource)
+		return r.fetchFromFile(ctx, source.File)
 	case "ConfigMap":
-		return r.fetchFromConfigMap(ctx, configMapSource)
+		return r.fetchFromConfigMap(ctx, configMapSource, source.ConfigMap)
 	case "Secret":
-		return r.fetchFromSecret(ctx, configMapSource)
+		return r.fetchFromSecret(ctx, configMapSource, source.Secret)
 	default:
-		return nil, fmt.Errorf("unsupported source type: %s", configMapSource.Spec.SourceType)
+		return nil, fmt.Errorf("unsupported source type: %s", source.Type)
 	}
 }
 
 // fetchFromGit retrieves configuration data from a Git repository
-func (r *ConfigMapSourceReconciler) fetchFromGit(ctx context.Context, configMapSource *configv1alpha1.ConfigMapSource) (map[string]string, error) {
+func (r *ConfigMapSourceReconciler) fetchFromGit(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, gitSource *configv1alpha1.GitSource, status *configv1alpha1.SourceStatus) (*sourceData, error) {
 	logger := log.FromContext(ctx)
-	if configMapSource.Spec.Git == nil {
+	if gitSource == nil {
 		return nil, fmt.Errorf("Git source configuration is missing")
 	}
 
-	// Create temporary directory for Git clone
+	// Setup authentication if needed
+	auth, err := r.gitAuth(ctx, configMapSource, gitSource)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open the shared mirror of the repository
+	repo, release, err := r.GitCache.open(gitSource.URL)
+	if err != nil {
+		return nil, err
+	}
+	fetched := false
+	defer func() { release(fetched) }()
+
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote of Git mirror: %w", err)
+	}
+
+	// Resolve the revision against the remote refs
+	// Listing with this source's credentials also checks that it may read the repository
+	// before anything is served from a mirror that other sources may have fetched
+	refs, err := remote.ListContext(ctx, &git.ListOptions{
+		Auth:          auth,
+		PeelingOption: git.AppendPeeled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote references: %w", err)
+	}
+	revision, err := resolveGitRevision(refs, gitSource.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Polled Git repository", "url", gitSource.URL, "revision", gitSource.Revision, "ref", revision.ref)
+
+	// Skip the fetch if the remote still points at the last synced commit
+	now := metav1.Now()
+	poll := status.GitPoll
+	if poll == nil {
+		poll = &configv1alpha1.GitPollStatus{}
+	}
+	poll.LastPollTime = &now
+	commitHash, unchanged := unchangedGitCommit(repo, revision, status.Revision)
+	if unchanged {
+		logger.Info("Remote revision unchanged, skipping fetch", "commit", commitHash)
+	} else {
+		fetched = true
+		fetchStart := time.Now()
+		commitHash, err = fetchGitRevision(ctx, repo, revision, auth)
+		gitFetchDuration.Observe(time.Since(fetchStart).Seconds())
+		if err != nil {
+			return nil, err
+		}
+		poll.LastFetchTime = &now
+	}
+	poll.Commit = commitHash.String()
+	status.GitPoll = poll
+
+	commit, err := repo.CommitObject(commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", commitHash, err)
+	}
+
+	// Extract the configuration files into a temporary directory
 	tempDir, err := ioutil.TempDir("", "git-config-")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 	defer os.RemoveAll(tempDir)
 
-	logger.Info("Cloning Git repository", "url", configMapSource.Spec.Git.URL, "revision", configMapSource.Spec.Git.Revision)
+	configPath, err := extractGitPath(commit, gitSource.Path, tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get configuration files from path
+	fetched, err := readConfigFiles(configPath, gitSource.FileReadOptions)
+	if err != nil {
+		return nil, err
+	}
 
-	// Setup authentication if needed
-	var auth *ssh.PublicKeys
-	if configMapSource.Spec.Git.AuthSecretRef != nil {
-		// Get auth secret
-		secretNamespace := configMapSource.Spec.Git.AuthSecretRef.Namespace
-		if secretNamespace == "" {
-			secretNamespace = configMapSource.Namespace
+	// Identify the content by commit, modification times only reflect the extraction
+	fetched.revision = &configv1alpha1.SourceRevision{
+		Ref:    revision.ref.String(),
+		Commit: commitHash.String(),
+	}
+	return fetched, nil
+}
+
+// gitRevision is a GitSource revision resolved against the refs advertised by the remote
+type gitRevision struct {
+	// ref is the branch or tag the revision matched, empty for commit SHAs
+	ref plumbing.ReferenceName
+	// commit is the commit the ref points at, or the full SHA given as the revision
+	commit plumbing.Hash
+	// shortSHA is set when the revision is an abbreviated commit SHA that can only be
+	// resolved once history has been fetched
+	shortSHA string
+}
+
+// resolveGitRevision resolves a revision as a branch, then a tag, then a full or abbreviated commit SHA
+func resolveGitRevision(refs []*plumbing.Reference, revision string) (*gitRevision, error) {
+	hashes := make(map[plumbing.ReferenceName]plumbing.Hash, len(refs))
+	for _, ref := range refs {
+		if ref.Type() == plumbing.HashReference {
+			hashes[ref.Name()] = ref.Hash()
 		}
+	}
+
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(revision),
+		plumbing.NewTagReferenceName(revision),
+	} {
+		hash, ok := hashes[name]
+		if !ok {
+			continue
+		}
+		// Annotated tags point at a tag object, the peeled entry points at the commit
+		if peeled, ok := hashes[name+"^{}"]; ok {
+			hash = peeled
+		}
+		return &gitRevision{ref: name, commit: hash}, nil
+	}
+
+	if plumbing.IsHash(revision) {
+		return &gitRevision{commit: plumbing.NewHash(revision)}, nil
+	}
+	if isShortSHA(revision) {
+		return &gitRevision{shortSHA: strings.ToLower(revision)}, nil
+	}
+
+	return nil, fmt.Errorf("revision %s is not a branch, tag or commit SHA in the remote repository", revision)
+}
+
+// isShortSHA reports whether revision looks like an abbreviated commit SHA
+func isShortSHA(revision string) bool {
+	if len(revision) < 4 || len(revision) >= 40 {
+		return false
+	}
+	for _, c := range revision {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// unchangedGitCommit returns the last synced commit if the resolved revision still points
+// at it and the mirror already has its objects
+func unchangedGitCommit(repo *git.Repository, revision *gitRevision, lastRevision *configv1alpha1.SourceRevision) (plumbing.Hash, bool) {
+	if lastRevision == nil || !plumbing.IsHash(lastRevision.Commit) {
+		return plumbing.ZeroHash, false
+	}
+
+	lastCommit := plumbing.NewHash(lastRevision.Commit)
+	if revision.shortSHA != "" {
+		if !strings.HasPrefix(lastRevision.Commit, revision.shortSHA) {
+			return plumbing.ZeroHash, false
+		}
+	} else if revision.commit != lastCommit {
+		return plumbing.ZeroHash, false
+	}
 
-		var secret corev1.Secret
-		secretName := types.NamespacedName{
-			Name:      configMapSource.Spec.Git.AuthSecretRef.Name,
-			Namespace: secretNamespace,
+	// The mirror may have been evicted since the last sync
+	if _, err := repo.CommitObject(lastCommit); err != nil {
+		return plumbing.ZeroHash, false
+	}
+	return lastCommit, true
+}
+
+// fetchGitRevision fetches the mirror and returns the commit a resolved revision points at
+func fetchGitRevision(ctx context.Context, repo *git.Repository, revision *gitRevision, auth transport.AuthMethod) (plumbing.Hash, error) {
+	// Incrementally fetch all branches and tags into the mirror
+	if err := fetchGitRefSpecs(ctx, repo, auth); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to fetch repository: %w", err)
+	}
+
+	// Branches and tags were already resolved from the remote refs
+	if revision.ref != "" {
+		return revision.commit, nil
+	}
+
+	// Full SHAs not reachable from any branch or tag are fetched directly,
+	// which needs the server to allow fetching unadvertised commits
+	if !revision.commit.IsZero() {
+		if _, err := repo.CommitObject(revision.commit); err != nil {
+			refSpec := config.RefSpec(fmt.Sprintf("%s:%s%s", revision.commit, gitPinnedRefPrefix, revision.commit))
+			if err := fetchGitRefSpecs(ctx, repo, auth, refSpec); err != nil {
+				return plumbing.ZeroHash, fmt.Errorf("commit %s not found in repository: %w", revision.commit, err)
+			}
 		}
+		return revision.commit, nil
+	}
+
+	commit, err := repo.ResolveRevision(plumbing.Revision(revision.shortSHA))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("commit %s not found in repository: %w", revision.shortSHA, err)
+	}
+	return *commit, nil
+}
+
+// fetchGitRefSpecs fetches refSpecs from the origin remote, or the mirror refspecs if none are given
+func fetchGitRefSpecs(ctx context.Context, repo *git.Repository, auth transport.AuthMethod, refSpecs ...config.RefSpec) error {
+	err := repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   refSpecs,
+		Auth:       auth,
+		Tags:       git.NoTags,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+	return nil
+}
+
+// gitAuth builds the transport credentials for a Git source from its AuthSecretRef.
+// SSH URLs use the private key stored under Key. HTTP(S) URLs use a bearer token
+// from TokenKey, a username and password from UsernameKey/PasswordKey, or a
+// "username:password" value stored under Key.
+func (r *ConfigMapSourceReconciler) gitAuth(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, gitSource *configv1alpha1.GitSource) (transport.AuthMethod, error) {
+	authRef := gitSource.AuthSecretRef
+	if authRef == nil {
+		return nil, nil
+	}
+
+	endpoint, err := transport.NewEndpoint(gitSource.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repository URL: %w", err)
+	}
+
+	// Get auth secret
+	secretNamespace := authRef.Namespace
+	if secretNamespace == "" {
+		secretNamespace = configMapSource.GetNamespace()
+	}
+	if secretNamespace == "" {
+		return nil, errMissingNamespace
+	}
 
-		if err := r.Get(ctx, secretName, &secret); err != nil {
-			return nil, fmt.Errorf("failed to get auth secret: %w", err)
+	var secret corev1.Secret
+	secretName := types.NamespacedName{
+		Name:      authRef.Name,
+		Namespace: secretNamespace,
+	}
+
+	if err := r.Get(ctx, secretName, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get auth secret: %w", err)
+	}
+
+	switch endpoint.Protocol {
+	case "ssh":
+		if authRef.UsernameKey != "" || authRef.PasswordKey != "" || authRef.TokenKey != "" {
+			return nil, invalidGitAuth("usernameKey, passwordKey and tokenKey are only supported for HTTPS URLs, use key for an SSH private key")
+		}
+		if authRef.Key == "" {
+			return nil, invalidGitAuth("key must reference an SSH private key for SSH URLs")
 		}
 
 		// Get SSH key from secret
-		sshKeyData, ok := secret.Data[configMapSource.Spec.Git.AuthSecretRef.Key]
-		if !ok {
-			return nil, fmt.Errorf("SSH key not found in secret at key: %s", configMapSource.Spec.Git.AuthSecretRef.Key)
+		sshKeyData, err := secretValue(&secret, authRef.Key)
+		if err != nil {
+			return nil, err
 		}
 
 		// Create SSH auth from private key
-		auth, err = ssh.NewPublicKeysFromFile("git", "", "")
-		if err != nil {
-			return nil, fmt.Errorf("failed to create SSH auth: %w", err)
+		user := endpoint.User
+		if user == "" {
+			user = "git"
 		}
-		auth.Signer, err = ssh.NewSignerFromSigner(sshKeyData)
+		auth, err := ssh.NewPublicKeys(user, sshKeyData, "")
 		if err != nil {
-			return nil, fmt.Errorf("failed to create signer from SSH key: %w", err)
+			return nil, invalidGitAuth(fmt.Sprintf("key %s does not contain a valid SSH private key: %v", authRef.Key, err))
 		}
-	}
+		return auth, nil
 
-	// Clone options
-	cloneOptions := &git.CloneOptions{
-		URL:           configMapSource.Spec.Git.URL,
-		SingleBranch:  true,
-		ReferenceName: plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", configMapSource.Spec.Git.Revision)),
-		Auth:          auth,
-		Depth:         1,
+	case "http", "https":
+		switch {
+		case authRef.TokenKey != "":
+			if authRef.UsernameKey != "" || authRef.PasswordKey != "" || authRef.Key != "" {
+				return nil, invalidGitAuth("tokenKey cannot be combined with key, usernameKey or passwordKey")
+			}
+			token, err := secretValue(&secret, authRef.TokenKey)
+			if err != nil {
+				return nil, err
+			}
+			return &githttp.TokenAuth{Token: strings.TrimSpace(string(token))}, nil
+
+		case authRef.UsernameKey != "" || authRef.PasswordKey != "":
+			if authRef.UsernameKey == "" || authRef.PasswordKey == "" {
+				return nil, invalidGitAuth("usernameKey and passwordKey must be set together")
+			}
+			if authRef.Key != "" {
+				return nil, invalidGitAuth("key cannot be combined with usernameKey and passwordKey")
+			}
+			username, err := secretValue(&secret, authRef.UsernameKey)
+			if err != nil {
+				return nil, err
+			}
+			password, err := secretValue(&secret, authRef.PasswordKey)
+			if err != nil {
+				return nil, err
+			}
+			return &githttp.BasicAuth{
+				Username: strings.TrimSpace(string(username)),
+				Password: strings.TrimSpace(string(password)),
+			}, nil
+
+		case authRef.Key != "":
+			value, err := secretValue(&secret, authRef.Key)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.HasPrefix(bytes.TrimSpace(value), []byte("-----BEGIN")) {
+				return nil, invalidGitAuth(fmt.Sprintf("key %s contains an SSH private key but the repository URL uses %s", authRef.Key, endpoint.Protocol))
+			}
+			username, password, ok := strings.Cut(strings.TrimSpace(string(value)), ":")
+			if !ok {
+				return nil, invalidGitAuth(fmt.Sprintf("key %s must contain username:password for %s URLs", authRef.Key, endpoint.Protocol))
+			}
+			return &githttp.BasicAuth{Username: username, Password: password}, nil
+
+		default:
+			return nil, invalidGitAuth("authSecretRef must set tokenKey, usernameKey and passwordKey, or key for HTTPS URLs")
+		}
+
+	default:
+		return nil, invalidGitAuth(fmt.Sprintf("authentication is not supported for %s URLs", endpoint.Protocol))
 	}
+}
 
-	// Clone repository
-	_, err = git.PlainClone(tempDir, false, cloneOptions)
-	if err != nil {
-		return nil, fmt.Errorf("failed to clone repository: %w", err)
+// secretValue returns the value stored under key in the Git auth secret
+func secretValue(secret *corev1.Secret, key string) ([]byte, error) {
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, invalidGitAuth(fmt.Sprintf("key %s not found in secret %s", key, secret.Name))
 	}
+	return value, nil
+}
 
-	// Get configuration files from path
-	configPath := filepath.Join(tempDir, configMapSource.Spec.Git.Path)
-	return readConfigFiles(configPath)
+// invalidGitAuth reports a Git auth secret whose contents do not match the repository URL
+func invalidGitAuth(message string) error {
+	return &reasonError{reason: "InvalidGitAuth", err: errors.New(message)}
 }
 
 // fetchFromFile retrieves configuration data from a local file
-func (r *ConfigMapSourceReconciler) fetchFromFile(ctx context.Context, configMapSource *configv1alpha1.ConfigMapSource) (map[string]string, error) {
+func (r *ConfigMapSourceReconciler) fetchFromFile(ctx context.Context, fileSource *configv1alpha1.FileSource) (*sourceData, error) {
 	logger := log.FromContext(ctx)
-	if configMapSource.Spec.File == nil {
+	if fileSource == nil {
 		return nil, fmt.Errorf("File source configuration is missing")
 	}
 
-	logger.Info("Reading configuration from file", "path", configMapSource.Spec.File.Path)
-	return readConfigFiles(configMapSource.Spec.File.Path)
+	logger.Info("Reading configuration from file", "path", fileSource.Path)
+	return readConfigFiles(fileSource.Path, fileSource.FileReadOptions)
 }
 
 // fetchFromConfigMap retrieves configuration data from another ConfigMap
-func (r *ConfigMapSourceReconciler) fetchFromConfigMap(ctx context.Context, configMapSource *configv1alpha1.ConfigMapSource) (map[string]string, error) {
+func (r *ConfigMapSourceReconciler) fetchFromConfigMap(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, configMapSpec *configv1alpha1.ConfigMapSource) (*sourceData, error) {
 	logger := log.FromContext(ctx)
-	if configMapSource.Spec.ConfigMap == nil {
+	if configMapSpec == nil {
 		return nil, fmt.Errorf("ConfigMap source configuration is missing")
 	}
 
 	// Determine source namespace
-	sourceNamespace := configMapSource.Spec.ConfigMap.Namespace
+	sourceNamespace := configMapSpec.Namespace
 	if sourceNamespace == "" {
-		sourceNamespace = configMapSource.Namespace
+		sourceNamespace = configMapSource.GetNamespace()
+	}
+	if sourceNamespace == "" {
+		return nil, errMissingNamespace
 	}
 
 	// Fetch source ConfigMap
 	var sourceConfigMap corev1.ConfigMap
 	sourceConfigMapName := types.NamespacedName{
-		Name:      configMapSource.Spec.ConfigMap.Name,
+		Name:      configMapSpec.Name,
 		Namespace: sourceNamespace,
 	}
 
@@ -364,39 +1028,55 @@ func (r *ConfigMapSourceReconciler) fetchFromConfigMap(ctx context.Context, conf
 
 	// Filter keys if specified
 	resultData := make(map[string]string)
-	if len(configMapSource.Spec.ConfigMap.Keys) > 0 {
-		for _, key := range configMapSource.Spec.ConfigMap.Keys {
+	resultBinaryData := make(map[string][]byte)
+	if len(configMapSpec.Keys) > 0 {
+		for _, key := range configMapSpec.Keys {
 			if value, exists := sourceConfigMap.Data[key]; exists {
 				resultData[key] = value
 			}
+			if value, exists := sourceConfigMap.BinaryData[key]; exists {
+				resultBinaryData[key] = value
+			}
 		}
 	} else {
 		// Copy all keys
 		for key, value := range sourceConfigMap.Data {
 			resultData[key] = value
 		}
+		for key, value := range sourceConfigMap.BinaryData {
+			resultBinaryData[key] = value
+		}
 	}
 
-	return resultData, nil
+	return &sourceData{
+		data:       resultData,
+		binaryData: resultBinaryData,
+		revision: &configv1alpha1.SourceRevision{
+			ResourceVersion: sourceConfigMap.ResourceVersion,
+		},
+	}, nil
 }
 
 // fetchFromSecret retrieves configuration data from a Secret
-func (r *ConfigMapSourceReconciler) fetchFromSecret(ctx context.Context, configMapSource *configv1alpha1.ConfigMapSource) (map[string]string, error) {
+func (r *ConfigMapSourceReconciler) fetchFromSecret(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, secretSpec *configv1alpha1.SecretSource) (*sourceData, error) {
 	logger := log.FromContext(ctx)
-	if configMapSource.Spec.Secret == nil {
+	if secretSpec == nil {
 		return nil, fmt.Errorf("Secret source configuration is missing")
 	}
 
 	// Determine source namespace
-	sourceNamespace := configMapSource.Spec.Secret.Namespace
+	sourceNamespace := secretSpec.Namespace
+	if sourceNamespace == "" {
+		sourceNamespace = configMapSource.GetNamespace()
+	}
 	if sourceNamespace == "" {
-		sourceNamespace = configMapSource.Namespace
+		return nil, errMissingNamespace
 	}
 
 	// Fetch source Secret
 	var sourceSecret corev1.Secret
 	sourceSecretName := types.NamespacedName{
-		Name:      configMapSource.Spec.Secret.Name,
+		Name:      secretSpec.Name,
 		Namespace: sourceNamespace,
 	}
 
@@ -406,69 +1086,162 @@ func (r *ConfigMapSourceReconciler) fetchFromSecret(ctx context.Context, configM
 	}
 
 	// Filter keys if specified
-	resultData := make(map[string]string)
-	if len(configMapSource.Spec.Secret.Keys) > 0 {
-		for _, key := range configMapSource.Spec.Secret.Keys {
+	result := &sourceData{
+		data:       make(map[string]string),
+		binaryData: make(map[string][]byte),
+		revision: &configv1alpha1.SourceRevision{
+			ResourceVersion: sourceSecret.ResourceVersion,
+		},
+	}
+	if len(secretSpec.Keys) > 0 {
+		for _, key := range secretSpec.Keys {
 			if value, exists := sourceSecret.Data[key]; exists {
-				resultData[key] = string(value)
+				result.set(key, value, false)
 			}
 		}
 	} else {
 		// Copy all keys
 		for key, value := range sourceSecret.Data {
-			resultData[key] = string(value)
+			result.set(key, value, false)
 		}
 	}
 
-	return resultData, nil
+	return result, nil
 }
 
-// readConfigFiles reads configuration files from a directory
-func readConfigFiles(path string) (map[string]string, error) {
-	configData := make(map[string]string)
+// readConfigFiles reads configuration files from a file or directory
+// The revision records the most recent modification time of the files read
+func readConfigFiles(path string, options configv1alpha1.FileReadOptions) (*sourceData, error) {
+	result := &sourceData{
+		data:       make(map[string]string),
+		binaryData: make(map[string][]byte),
+	}
+	var modTime time.Time
+
+	mapper, err := newKeyMapper(options.KeyMapping)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateFilePatterns(options); err != nil {
+		return nil, err
+	}
 
 	fileInfo, err := os.Stat(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat path: %w", err)
 	}
 
-	if fileInfo.IsDir() {
-		// Read all files in directory
-		files, err := ioutil.ReadDir(path)
+	if !fileInfo.IsDir() {
+		// Skip the file if it is filtered out
+		relPath := filepath.Base(path)
+		if !includeFile(relPath, options.Include, options.Exclude) {
+			return result, nil
+		}
+
+		// Read single file
+		content, err := ioutil.ReadFile(path)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read directory: %w", err)
+			return nil, fmt.Errorf("failed to read file: %w", err)
 		}
 
-		for _, file := range files {
-			if file.IsDir() {
-				continue // Skip subdirectories
-			}
+		// Map the base filename to the key
+		key, err := mapper.key(relPath)
+		if err != nil {
+			return nil, err
+		}
+		result.set(key, content, matchesAny(relPath, options.BinaryFiles))
+		result.revision = &configv1alpha1.SourceRevision{
+			ModTime: &metav1.Time{Time: fileInfo.ModTime()},
+		}
+		return result, nil
+	}
 
-			filePath := filepath.Join(path, file.Name())
-			content, err := ioutil.ReadFile(filePath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	// Read all files in directory, and in subdirectories if recursive
+	keyPaths := make(map[string]string)
+	err = filepath.Walk(path, func(filePath string, file os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read directory: %w", err)
+		}
+		if file.IsDir() {
+			if filePath != path && !options.Recursive {
+				return filepath.SkipDir // Skip subdirectories
 			}
+			return nil
+		}
 
-			configData[file.Name()] = string(content)
+		relPath, err := filepath.Rel(path, filePath)
+		if err != nil {
+			return err
 		}
-	} else {
-		// Read single file
-		content, err := ioutil.ReadFile(path)
+		relPath = filepath.ToSlash(relPath)
+		if !includeFile(relPath, options.Include, options.Exclude) {
+			return nil
+		}
+
+		key, err := mapper.key(relPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read file: %w", err)
+			return err
+		}
+		if otherPath, exists := keyPaths[key]; exists {
+			return invalidKeyMapping(fmt.Sprintf("files %s and %s both map to key %s", otherPath, relPath, key))
+		}
+		keyPaths[key] = relPath
+
+		content, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+
+		result.set(key, content, matchesAny(relPath, options.BinaryFiles))
+		if file.ModTime().After(modTime) {
+			modTime = file.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.revision = &configv1alpha1.SourceRevision{
+		ModTime: &metav1.Time{Time: modTime},
+	}
+	return result, nil
+}
+
+// validateFilePatterns checks the glob patterns in the read options are well formed
+func validateFilePatterns(options configv1alpha1.FileReadOptions) error {
+	for _, patterns := range [][]string{options.Include, options.Exclude, options.BinaryFiles} {
+		for _, pattern := range patterns {
+			if !doublestar.ValidatePattern(pattern) {
+				return &reasonError{reason: "InvalidFilePattern", err: fmt.Errorf("invalid glob pattern: %s", pattern)}
+			}
 		}
+	}
+	return nil
+}
 
-		// Use base filename as the key
-		fileName := filepath.Base(path)
-		configData[fileName] = string(content)
+// includeFile reports whether a slash-separated path relative to the source path
+// matches the include patterns and none of the exclude patterns
+func includeFile(relPath string, include, exclude []string) bool {
+	if len(include) > 0 && !matchesAny(relPath, include) {
+		return false
 	}
+	return !matchesAny(relPath, exclude)
+}
 
-	return configData, nil
+// matchesAny reports whether relPath matches any of the glob patterns
+// Patterns must have been checked with validateFilePatterns
+func matchesAny(relPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if doublestar.MatchUnvalidated(pattern, relPath) {
+			return true
+		}
+	}
+	return false
 }
 
 // calculateConfigHash creates a hash of the configuration data for change detection
-func calculateConfigHash(configData map[string]string) string {
+func calculateConfigHash(configData map[string]string, binaryData map[string][]byte) string {
 	hash := sha256.New()
 
 	// Sort keys for consistent hashing
@@ -484,13 +1257,107 @@ func calculateConfigHash(configData map[string]string) string {
 		hash.Write([]byte(configData[key]))
 	}
 
+	// Hash binary data separately so text-only data keeps its previous hash
+	if len(binaryData) > 0 {
+		binaryKeys := make([]string, 0, len(binaryData))
+		for k := range binaryData {
+			binaryKeys = append(binaryKeys, k)
+		}
+		sort.Strings(binaryKeys)
+
+		hash.Write([]byte("binaryData"))
+		for _, key := range binaryKeys {
+			hash.Write([]byte(key))
+			hash.Write(binaryData[key])
+		}
+	}
+
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
+// calculateKeyDigests creates a sha256 digest of each value in the configuration data
+func calculateKeyDigests(configData map[string]string, binaryData map[string][]byte) map[string]string {
+	digests := make(map[string]string, len(configData)+len(binaryData))
+	for key, value := range configData {
+		sum := sha256.Sum256([]byte(value))
+		digests[key] = hex.EncodeToString(sum[:])
+	}
+	for key, value := range binaryData {
+		sum := sha256.Sum256(value)
+		digests[key] = hex.EncodeToString(sum[:])
+	}
+	return digests
+}
+
+// calculateRenderedKeyDigests creates the key digests of the content rendered for each namespace
+// A key with the same value in every namespace has the digest of that value, otherwise the
+// digest covers the digest of each namespace, so a change in any namespace changes it
+func calculateRenderedKeyDigests(rendered map[string]*desiredTarget) map[string]string {
+	namespaces := make([]string, 0, len(rendered))
+	for namespace := range rendered {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	// Digests of each key, in namespace order
+	keyNamespaces := make(map[string][]string)
+	keyDigests := make(map[string][]string)
+	for _, namespace := range namespaces {
+		for key, digest := range calculateKeyDigests(rendered[namespace].data, rendered[namespace].binaryData) {
+			keyNamespaces[key] = append(keyNamespaces[key], namespace)
+			keyDigests[key] = append(keyDigests[key], digest)
+		}
+	}
+
+	digests := make(map[string]string, len(keyDigests))
+	for key, perNamespace := range keyDigests {
+		uniform := len(perNamespace) == len(namespaces)
+		for _, digest := range perNamespace {
+			uniform = uniform && digest == perNamespace[0]
+		}
+		if uniform {
+			digests[key] = perNamespace[0]
+			continue
+		}
+		hash := sha256.New()
+		for i, digest := range perNamespace {
+			hash.Write([]byte(keyNamespaces[key][i]))
+			hash.Write([]byte(digest))
+		}
+		digests[key] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return digests
+}
+
+// diffKeyDigests compares the key digests of the previous and current sync
+func diffKeyDigests(previous, current map[string]string) *configv1alpha1.KeyChanges {
+	changes := &configv1alpha1.KeyChanges{}
+	for key, digest := range current {
+		previousDigest, exists := previous[key]
+		switch {
+		case !exists:
+			changes.Added = append(changes.Added, key)
+		case previousDigest != digest:
+			changes.Changed = append(changes.Changed, key)
+		}
+	}
+	for key := range previous {
+		if _, exists := current[key]; !exists {
+			changes.Removed = append(changes.Removed, key)
+		}
+	}
+
+	// Sort keys for stable status output
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+	return changes
+}
+
 // requeueBasedOnRefreshInterval determines when to requeue based on refresh interval
-func (r *ConfigMapSourceReconciler) requeueBasedOnRefreshInterval(configMapSource *configv1alpha1.ConfigMapSource) (ctrl.Result, error) {
-	if configMapSource.Spec.RefreshInterval != nil && *configMapSource.Spec.RefreshInterval > 0 {
-		interval := time.Duration(*configMapSource.Spec.RefreshInterval) * time.Second
+func (r *ConfigMapSourceReconciler) requeueBasedOnRefreshInterval(configMapSource configv1alpha1.GenericConfigMapSource) (ctrl.Result, error) {
+	if configMapSource.GetSpec().RefreshInterval != nil && *configMapSource.GetSpec().RefreshInterval > 0 {
+		interval := time.Duration(*configMapSource.GetSpec().RefreshInterval) * time.Second
 		return ctrl.Result{RequeueAfter: interval}, nil
 	}
 
@@ -498,16 +1365,32 @@ func (r *ConfigMapSourceReconciler) requeueBasedOnRefreshInterval(configMapSourc
 	return ctrl.Result{}, nil
 }
 
+// updateFailedStatus marks the ConfigMapSource as not ready and persists the status
+func (r *ConfigMapSourceReconciler) updateFailedStatus(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, reason, message string) {
+	syncFailures.WithLabelValues(sourceTypeLabel(configMapSource), reason).Inc()
+	r.setStatusCondition(configMapSource, metav1.Condition{
+		Type:    "Ready",
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	if err := r.Status().Update(ctx, configMapSource); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update ConfigMapSource status", "reason", reason)
+		return
+	}
+	sourceStates.observe(configMapSource)
+}
+
 // setStatusCondition updates a status condition
-func (r *ConfigMapSourceReconciler) setStatusCondition(configMapSource *configv1alpha1.ConfigMapSource, condition metav1.Condition) {
+func (r *ConfigMapSourceReconciler) setStatusCondition(configMapSource configv1alpha1.GenericConfigMapSource, condition metav1.Condition) {
 	// Initialize conditions if nil
-	if configMapSource.Status.Conditions == nil {
-		configMapSource.Status.Conditions = []metav1.Condition{}
+	if configMapSource.GetStatus().Conditions == nil {
+		configMapSource.GetStatus().Conditions = []metav1.Condition{}
 	}
 
 	// Find existing condition
 	conditionIndex := -1
-	for i, cond := range configMapSource.Status.Conditions {
+	for i, cond := range configMapSource.GetStatus().Conditions {
 		if cond.Type == condition.Type {
 			conditionIndex = i
 			break
@@ -516,31 +1399,269 @@ func (r *ConfigMapSourceReconciler) setStatusCondition(configMapSource *configv1
 
 	// Set condition
 	condition.LastTransitionTime = metav1.Now()
-	condition.ObservedGeneration = configMapSource.Generation
+	condition.ObservedGeneration = configMapSource.GetGeneration()
 
 	if conditionIndex != -1 {
 		// Update existing condition
-		configMapSource.Status.Conditions[conditionIndex] = condition
+		configMapSource.GetStatus().Conditions[conditionIndex] = condition
 	} else {
 		// Add new condition
-		configMapSource.Status.Conditions = append(configMapSource.Status.Conditions, condition)
+		configMapSource.GetStatus().Conditions = append(configMapSource.GetStatus().Conditions, condition)
 	}
 }
 
+// errMissingNamespace reports a namespace left out of a cluster-scoped source,
+// which has no namespace of its own to default to
+var errMissingNamespace = &reasonError{reason: "MissingNamespace", err: errors.New("namespace must be set for ClusterConfigMapSource")}
+
+// checkNamespaces rejects a namespaced source that reads or writes outside its own namespace
+// The operator acts with its own permissions, so otherwise anyone allowed to create a ConfigMapSource
+// in one namespace could read any Secret and write ConfigMaps anywhere
+// Cluster-scoped sources are not confined, creating them is up to cluster admins
+func checkNamespaces(configMapSource configv1alpha1.GenericConfigMapSource) error {
+	own := configMapSource.GetNamespace()
+	if own == "" {
+		return nil
+	}
+	outside := func(field, namespace string) error {
+		if namespace == "" || namespace == own {
+			return nil
+		}
+		return &reasonError{
+			reason: "NamespaceNotAllowed",
+			err:    fmt.Errorf("%s %s is outside namespace %s, only a ClusterConfigMapSource may use other namespaces", field, namespace, own),
+		}
+	}
+
+	spec := configMapSource.GetSpec()
+	for i, source := range specSources(configMapSource) {
+		field := "spec"
+		if len(spec.Sources) > 0 {
+			field = fmt.Sprintf("spec.sources[%d]", i)
+		}
+		if source.ConfigMap != nil {
+			if err := outside(field+".configMap.namespace", source.ConfigMap.Namespace); err != nil {
+				return err
+			}
+		}
+		if source.Secret != nil {
+			if err := outside(field+".secret.namespace", source.Secret.Namespace); err != nil {
+				return err
+			}
+		}
+		if source.Git != nil && source.Git.AuthSecretRef != nil {
+			if err := outside(field+".git.authSecretRef.namespace", source.Git.AuthSecretRef.Namespace); err != nil {
+				return err
+			}
+		}
+	}
+	if spec.Template != nil {
+		for i, ref := range spec.Template.ValuesFrom {
+			if err := outside(fmt.Sprintf("spec.template.valuesFrom[%d].namespace", i), ref.Namespace); err != nil {
+				return err
+			}
+		}
+	}
+	if spec.Substitution != nil {
+		for i, ref := range spec.Substitution.VariablesFrom {
+			if err := outside(fmt.Sprintf("spec.substitution.variablesFrom[%d].namespace", i), ref.Namespace); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := outside("spec.targetNamespace", spec.TargetNamespace); err != nil {
+		return err
+	}
+	for i, namespace := range spec.TargetNamespaces {
+		if err := outside(fmt.Sprintf("spec.targetNamespaces[%d]", i), namespace); err != nil {
+			return err
+		}
+	}
+	if spec.TargetNamespaceSelector != nil {
+		return &reasonError{
+			reason: "NamespaceNotAllowed",
+			err:    errors.New("spec.targetNamespaceSelector selects namespaces outside the source's own, only a ClusterConfigMapSource may use it"),
+		}
+	}
+	return nil
+}
+
+// reasonError attaches a status condition reason to an error
+type reasonError struct {
+	reason string
+	err    error
+}
+
+func (e *reasonError) Error() string { return e.err.Error() }
+
+func (e *reasonError) Unwrap() error { return e.err }
+
+// errorReason returns the condition reason attached to err, or fallback if there is none
+func errorReason(err error, fallback string) string {
+	var reasonErr *reasonError
+	if errors.As(err, &reasonErr) {
+		return reasonErr.reason
+	}
+	return fallback
+}
+
 // isOwnedBy checks if a ConfigMap is owned by a ConfigMapSource
-func isOwnedBy(obj *corev1.ConfigMap, owner *configv1alpha1.ConfigMapSource) bool {
+// Targets in other namespaces and of cluster-scoped sources are tracked by label instead of an owner reference
+func isOwnedBy(obj *corev1.ConfigMap, owner configv1alpha1.GenericConfigMapSource) bool {
 	for _, ref := range obj.OwnerReferences {
-		if ref.UID == owner.UID {
+		if ref.UID == owner.GetUID() {
 			return true
 		}
 	}
-	return false
+	return obj.Labels[ownerUIDLabel] == string(owner.GetUID())
+}
+
+// sourceConfigMapIndexValue returns the namespace/name of the ConfigMaps a ConfigMapSource reads from
+func sourceConfigMapIndexValue(obj client.Object) []string {
+	configMapSource := obj.(configv1alpha1.GenericConfigMapSource)
+
+	var values []string
+	for _, source := range specSources(configMapSource) {
+		if source.Type != "ConfigMap" || source.ConfigMap == nil {
+			continue
+		}
+		sourceNamespace := source.ConfigMap.Namespace
+		if sourceNamespace == "" {
+			sourceNamespace = configMapSource.GetNamespace()
+		}
+		if sourceNamespace == "" {
+			continue
+		}
+		values = append(values, types.NamespacedName{Namespace: sourceNamespace, Name: source.ConfigMap.Name}.String())
+	}
+	return append(values, valuesIndexValues(configMapSource, "ConfigMap")...)
+}
+
+// sourceSecretIndexValue returns the namespace/name of the Secrets a ConfigMapSource reads from
+func sourceSecretIndexValue(obj client.Object) []string {
+	configMapSource := obj.(configv1alpha1.GenericConfigMapSource)
+
+	var values []string
+	for _, source := range specSources(configMapSource) {
+		if source.Type != "Secret" || source.Secret == nil {
+			continue
+		}
+		sourceNamespace := source.Secret.Namespace
+		if sourceNamespace == "" {
+			sourceNamespace = configMapSource.GetNamespace()
+		}
+		if sourceNamespace == "" {
+			continue
+		}
+		values = append(values, types.NamespacedName{Namespace: sourceNamespace, Name: source.Secret.Name}.String())
+	}
+	return append(values, valuesIndexValues(configMapSource, "Secret")...)
+}
+
+// valuesIndexValues returns the namespace/name of the objects of kind a ConfigMapSource reads values from
+func valuesIndexValues(configMapSource configv1alpha1.GenericConfigMapSource, kind string) []string {
+	var values []string
+	for _, reference := range valuesReferences(configMapSource) {
+		if reference.Kind != kind {
+			continue
+		}
+		referenceNamespace := reference.Namespace
+		if referenceNamespace == "" {
+			referenceNamespace = configMapSource.GetNamespace()
+		}
+		if referenceNamespace == "" {
+			continue
+		}
+		values = append(values, types.NamespacedName{Namespace: referenceNamespace, Name: reference.Name}.String())
+	}
+	return values
+}
+
+// requestsForIndex returns a map function enqueueing every source in the list returned
+// by newList whose index field references the changed object
+func (r *ConfigMapSourceReconciler) requestsForIndex(newList func() client.ObjectList, index string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+
+		configMapSources := newList()
+		if err := r.List(ctx, configMapSources, client.MatchingFields{index: name.String()}); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list ConfigMapSources referencing object", "index", index, "name", name)
+			return nil
+		}
+		return requestsForList(configMapSources, nil)
+	}
+}
+
+// requestsForList returns a reconcile request for every source in list accepted by filter
+// A nil filter accepts all sources
+func requestsForList(list client.ObjectList, filter func(configv1alpha1.GenericConfigMapSource) bool) []reconcile.Request {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(items))
+	for _, item := range items {
+		configMapSource, ok := item.(configv1alpha1.GenericConfigMapSource)
+		if !ok || (filter != nil && !filter(configMapSource)) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      configMapSource.GetName(),
+				Namespace: configMapSource.GetNamespace(),
+			},
+		})
+	}
+	return requests
+}
+
+// newConfigMapSourceList returns an empty ConfigMapSourceList
+func newConfigMapSourceList() client.ObjectList {
+	return &configv1alpha1.ConfigMapSourceList{}
 }
 
 // SetupWithManager sets up the controller with the Manager
 func (r *ConfigMapSourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.GitCache == nil {
+		gitCache, err := sharedGitCache()
+		if err != nil {
+			return err
+		}
+		r.GitCache = gitCache
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("configmapsource-controller")
+	}
+	r.Recorder = newDedupRecorder(r.Recorder, eventDedupWindow)
+
+	// Report the sources that exist at startup before they are reconciled
+	if err := mgr.Add(&sourceStateSeeder{reader: mgr.GetAPIReader(), newList: newConfigMapSourceList}); err != nil {
+		return err
+	}
+
+	// Index ConfigMapSources by the ConfigMap or Secret they read from so changes
+	// to a source object can be mapped back to them, and by UID for targets tracked by label
+	ctx := context.Background()
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &configv1alpha1.ConfigMapSource{}, sourceConfigMapIndex, sourceConfigMapIndexValue); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &configv1alpha1.ConfigMapSource{}, sourceSecretIndex, sourceSecretIndexValue); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &configv1alpha1.ConfigMapSource{}, ownerUIDIndex, ownerUIDIndexValue); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&configv1alpha1.ConfigMapSource{}).
+		// Status updates don't change the generation, so they don't queue another reconcile
+		// Labels are passed to templates, so changing them does
+		For(&configv1alpha1.ConfigMapSource{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
 		Owns(&corev1.ConfigMap{}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.requestsForOwnerLabel(newConfigMapSourceList))).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.requestsForIndex(newConfigMapSourceList, sourceConfigMapIndex))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.requestsForIndex(newConfigMapSourceList, sourceSecretIndex))).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.requestsForNamespace(newConfigMapSourceList))).
 		Complete(r)
 }
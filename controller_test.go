@@ -0,0 +1,111 @@
+// controllers/configmapsource_controller_test.go
+
+package controllers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+// TestNewSourceSyncs checks that a newly created source is synced without any further event,
+// after the reconcile adding its finalizer
+func TestNewSourceSyncs(t *testing.T) {
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		t.Fatalf("failed to start test environment: %v", err)
+	}
+	defer testEnv.Stop()
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := configv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	gitCache, err := NewGitCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&ConfigMapSourceReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		GitCache: gitCache,
+	}).SetupWithManager(mgr); err != nil {
+		t.Fatalf("failed to set up controller: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := mgr.Start(ctx); err != nil {
+			t.Errorf("failed to start manager: %v", err)
+		}
+	}()
+
+	k8sClient := mgr.GetClient()
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-source", Namespace: "default"},
+		Data:       map[string]string{"app.yaml": "replicas: 2"},
+	}
+	if err := k8sClient.Create(ctx, source); err != nil {
+		t.Fatalf("failed to create source ConfigMap: %v", err)
+	}
+	configMapSource := &configv1alpha1.ConfigMapSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: configv1alpha1.ConfigMapSourceSpec{
+			SourceType:      "ConfigMap",
+			ConfigMap:       &configv1alpha1.ConfigMapSource{Name: "app-source"},
+			TargetConfigMap: "app-config",
+		},
+	}
+	if err := k8sClient.Create(ctx, configMapSource); err != nil {
+		t.Fatalf("failed to create ConfigMapSource: %v", err)
+	}
+
+	// The target must appear well before the informer's periodic resync
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		var target corev1.ConfigMap
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: "app-config", Namespace: "default"}, &target)
+		if err == nil {
+			if target.Data["app.yaml"] != "replicas: 2" {
+				t.Fatalf("unexpected target data: %v", target.Data)
+			}
+			return
+		}
+		if client.IgnoreNotFound(err) != nil {
+			t.Fatalf("failed to get target ConfigMap: %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("target ConfigMap was not created")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,130 @@
+// controllers/deletion_policy.go
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+const (
+	// originalContentAnnotation holds the content an adopted target ConfigMap had before adoption
+	originalContentAnnotation = "configmapsource.config.example.com/original-content"
+	// adoptedAnnotation marks a target ConfigMap the source adopted rather than created
+	adoptedAnnotation = "configmapsource.config.example.com/adopted"
+	// maxSnapshotBytes bounds the snapshot so it fits within the annotation size limit
+	maxSnapshotBytes = 128 << 10
+)
+
+// targetSnapshot is the content of a target ConfigMap before it was adopted
+type targetSnapshot struct {
+	Data       map[string]string `json:"data,omitempty"`
+	BinaryData map[string][]byte `json:"binaryData,omitempty"`
+}
+
+// snapshotTarget returns the content of a target ConfigMap about to be adopted
+// Content too large for an annotation is only an error if it must be restored on deletion
+func snapshotTarget(configMapSource configv1alpha1.GenericConfigMapSource, target *corev1.ConfigMap) (string, error) {
+	snapshot, err := json.Marshal(targetSnapshot{
+		Data:       target.Data,
+		BinaryData: target.BinaryData,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot ConfigMap %s/%s: %w", target.Namespace, target.Name, err)
+	}
+	if len(snapshot) > maxSnapshotBytes {
+		if configMapSource.GetSpec().DeletionPolicy == "RestoreOriginal" {
+			return "", &reasonError{reason: "SnapshotTooLarge", err: fmt.Errorf("content of ConfigMap %s/%s is too large to restore on deletion (%d bytes, limit %d)",
+				target.Namespace, target.Name, len(snapshot), maxSnapshotBytes)}
+		}
+		return "", nil
+	}
+	return string(snapshot), nil
+}
+
+// releaseTarget applies the deletion policy of the source to a target ConfigMap it owns
+// Writes are made under the source's field manager, so the released target doesn't look
+// like it was written by another client
+func (r *ConfigMapSourceReconciler) releaseTarget(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, target *corev1.ConfigMap) error {
+	logger := log.FromContext(ctx)
+	targetConfigMapName := client.ObjectKeyFromObject(target)
+	fieldManager := targetFieldManager(configMapSource)
+
+	switch configMapSource.GetSpec().DeletionPolicy {
+	case "Orphan":
+		logger.Info("Orphaning owned ConfigMap", "name", targetConfigMapName)
+		if err := r.orphanTarget(ctx, configMapSource, target, fieldManager); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "TargetOrphaned", "Left target ConfigMap %s in place", targetConfigMapName)
+		return nil
+
+	case "RestoreOriginal":
+		snapshot, ok := target.Annotations[originalContentAnnotation]
+		if !ok && target.Annotations[adoptedAnnotation] == "true" {
+			// The content from before adoption was too large to snapshot, so leave the target
+			// in place rather than delete content the source didn't create
+			logger.Info("Orphaning adopted ConfigMap without original content", "name", targetConfigMapName)
+			if err := r.orphanTarget(ctx, configMapSource, target, fieldManager); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, "TargetNotRestored", "Left target ConfigMap %s in place, its original content was not recorded", targetConfigMapName)
+			return nil
+		}
+		if !ok {
+			// Targets created by the source have no original content to go back to
+			break
+		}
+		var original targetSnapshot
+		if err := json.Unmarshal([]byte(snapshot), &original); err != nil {
+			return fmt.Errorf("failed to read original content of ConfigMap %s: %w", targetConfigMapName, err)
+		}
+
+		logger.Info("Restoring original content of owned ConfigMap", "name", targetConfigMapName)
+		disown(target, configMapSource)
+		target.Data = original.Data
+		target.BinaryData = original.BinaryData
+		if err := r.Update(ctx, target, client.FieldOwner(fieldManager)); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "TargetRestored", "Restored original content of target ConfigMap %s", targetConfigMapName)
+		return nil
+	}
+
+	logger.Info("Deleting owned ConfigMap", "name", targetConfigMapName)
+	if err := r.Delete(ctx, target); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "TargetDeleted", "Deleted target ConfigMap %s", targetConfigMapName)
+	return nil
+}
+
+// orphanTarget removes the ownership marks of a source from a target ConfigMap, leaving its content in place
+func (r *ConfigMapSourceReconciler) orphanTarget(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, target *corev1.ConfigMap, fieldManager string) error {
+	patch := client.MergeFrom(target.DeepCopy())
+	disown(target, configMapSource)
+	return r.Patch(ctx, target, patch, client.FieldOwner(fieldManager))
+}
+
+// disown removes the ownership marks of a source from a target ConfigMap
+func disown(target *corev1.ConfigMap, configMapSource configv1alpha1.GenericConfigMapSource) {
+	ownerReferences := target.OwnerReferences[:0]
+	for _, ref := range target.OwnerReferences {
+		if ref.UID != configMapSource.GetUID() {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	target.OwnerReferences = ownerReferences
+
+	delete(target.Labels, ownerUIDLabel)
+	delete(target.Annotations, ownerAnnotation)
+	delete(target.Annotations, originalContentAnnotation)
+	delete(target.Annotations, adoptedAnnotation)
+}
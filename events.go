@@ -0,0 +1,117 @@
+// controllers/events.go
+
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// eventDedupWindow is how long a warning is suppressed after an identical one for the same object
+const eventDedupWindow = 10 * time.Minute
+
+// dedupRecorder wraps an EventRecorder and drops warnings that repeat the reason and message
+// of a warning recorded for the same object within the window, so a broken source on a short
+// refresh interval doesn't flood the event stream
+// Normal events are only recorded when something changed, and are passed through
+type dedupRecorder struct {
+	record.EventRecorder
+	window time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// newDedupRecorder wraps recorder, unless it already deduplicates events
+// A nil recorder drops all events
+func newDedupRecorder(recorder record.EventRecorder, window time.Duration) record.EventRecorder {
+	if recorder == nil {
+		recorder = discardRecorder{}
+	}
+	if _, ok := recorder.(*dedupRecorder); ok {
+		return recorder
+	}
+	return &dedupRecorder{
+		EventRecorder: recorder,
+		window:        window,
+		last:          make(map[string]time.Time),
+	}
+}
+
+// recorder returns the event recorder of the reconciler, or one that drops events if none is set,
+// e.g. for a reconciler used without SetupWithManager
+func (r *ConfigMapSourceReconciler) recorder() record.EventRecorder {
+	if r.Recorder == nil {
+		return discardRecorder{}
+	}
+	return r.Recorder
+}
+
+// discardRecorder is an EventRecorder that drops all events
+type discardRecorder struct{}
+
+// Event drops an event
+func (discardRecorder) Event(runtime.Object, string, string, string) {}
+
+// Eventf drops a formatted event
+func (discardRecorder) Eventf(runtime.Object, string, string, string, ...interface{}) {}
+
+// AnnotatedEventf drops an annotated event
+func (discardRecorder) AnnotatedEventf(runtime.Object, map[string]string, string, string, string, ...interface{}) {
+}
+
+// Event records an event unless it is a warning repeating a recent one
+func (d *dedupRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if eventtype == corev1.EventTypeWarning && d.seen(object, reason, message) {
+		return
+	}
+	d.EventRecorder.Event(object, eventtype, reason, message)
+}
+
+// Eventf records a formatted event unless it is a warning repeating a recent one
+func (d *dedupRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	d.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf records a formatted event with annotations unless it is a warning repeating a recent one
+// The annotations don't take part in deduplication
+func (d *dedupRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if eventtype == corev1.EventTypeWarning && d.seen(object, reason, message) {
+		return
+	}
+	d.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+}
+
+// seen reports whether the warning was recorded within the window, and remembers it otherwise
+func (d *dedupRecorder) seen(object runtime.Object, reason, message string) bool {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return false
+	}
+	key := fmt.Sprintf("%s/%s/%s", accessor.GetUID(), reason, message)
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if recorded, ok := d.last[key]; ok && now.Sub(recorded) < d.window {
+		return true
+	}
+	d.last[key] = now
+
+	// Forget expired events now and then so deleted objects don't pile up
+	if len(d.last) > 1000 {
+		for key, recorded := range d.last {
+			if now.Sub(recorded) >= d.window {
+				delete(d.last, key)
+			}
+		}
+	}
+	return false
+}
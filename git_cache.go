@@ -0,0 +1,326 @@
+// controllers/git_cache.go
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// defaultGitCacheMaxBytes bounds the Git cache when the reconciler is set up without one
+const defaultGitCacheMaxBytes = 1 << 30
+
+var (
+	defaultGitCacheOnce sync.Once
+	defaultGitCache     *GitCache
+	defaultGitCacheErr  error
+)
+
+// sharedGitCache returns the Git cache used by reconcilers set up without one
+// It is created once, so reconcilers never lock the same mirrors through different caches
+func sharedGitCache() (*GitCache, error) {
+	defaultGitCacheOnce.Do(func() {
+		defaultGitCache, defaultGitCacheErr = NewGitCache(filepath.Join(os.TempDir(), "configmapsource-git-cache"), defaultGitCacheMaxBytes)
+	})
+	return defaultGitCache, defaultGitCacheErr
+}
+
+// GitCache is an on-disk cache of bare Git mirrors shared by all ConfigMapSources.
+// Mirrors are keyed by repository URL and fetched incrementally, and the least
+// recently used mirrors are evicted once the cache grows beyond its size limit.
+type GitCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	mirrors map[string]*gitMirror
+}
+
+// gitMirror is a single bare mirror in the cache
+type gitMirror struct {
+	// mu serializes fetches and reads of the mirror
+	mu  sync.Mutex
+	dir string
+
+	// The fields below are guarded by GitCache.mu
+	lastUsed time.Time
+	size     int64
+	inUse    int
+}
+
+// NewGitCache creates a GitCache storing mirrors under dir
+// Mirrors left in dir by a previous run are picked up and count towards maxBytes
+// A maxBytes of 0 disables eviction
+func NewGitCache(dir string, maxBytes int64) (*GitCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create Git cache directory: %w", err)
+	}
+
+	cache := &GitCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		mirrors:  make(map[string]*gitMirror),
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Git cache directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		mirrorDir := filepath.Join(dir, entry.Name())
+		cache.mirrors[entry.Name()] = &gitMirror{
+			dir:      mirrorDir,
+			lastUsed: entry.ModTime(),
+			size:     dirSize(mirrorDir),
+		}
+	}
+
+	return cache, nil
+}
+
+// open returns the mirror for url, creating it if needed, with the mirror locked
+// The returned release function unlocks the mirror and must always be called, with changed
+// set if the mirror was fetched into. Only then is its size measured again, since walking
+// the mirror costs as much as the fetches the cache saves.
+func (c *GitCache) open(url string) (*git.Repository, func(changed bool), error) {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:16])
+
+	c.mu.Lock()
+	mirror, ok := c.mirrors[key]
+	if !ok {
+		mirror = &gitMirror{dir: filepath.Join(c.dir, key)}
+		c.mirrors[key] = mirror
+	}
+	mirror.inUse++
+	c.mu.Unlock()
+
+	mirror.mu.Lock()
+	// A mirror initialized by this open has changed even without a fetch
+	initialized := false
+	release := func(changed bool) {
+		size := mirror.size
+		if changed || initialized {
+			size = dirSize(mirror.dir)
+		}
+		mirror.mu.Unlock()
+
+		c.mu.Lock()
+		// Mirrors only grow by cloning and fetching, pruning happens through eviction
+		if grown := size - mirror.size; grown > 0 {
+			gitFetchBytes.Add(float64(grown))
+		}
+		mirror.inUse--
+		mirror.lastUsed = time.Now()
+		mirror.size = size
+		c.mu.Unlock()
+
+		c.evict()
+	}
+
+	repo, initialized, err := openGitMirror(mirror.dir, url)
+	if err != nil {
+		release(true)
+		return nil, nil, err
+	}
+	return repo, release, nil
+}
+
+// evict removes least recently used mirrors until the cache fits within maxBytes
+// Mirrors that are in use are never evicted
+func (c *GitCache) evict() {
+	if c.maxBytes <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total int64
+	keys := make([]string, 0, len(c.mirrors))
+	for key, mirror := range c.mirrors {
+		total += mirror.size
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.mirrors[keys[i]].lastUsed.Before(c.mirrors[keys[j]].lastUsed)
+	})
+
+	for _, key := range keys {
+		if total <= c.maxBytes {
+			return
+		}
+		mirror := c.mirrors[key]
+		if mirror.inUse > 0 {
+			continue
+		}
+		// Failing to remove a mirror only wastes disk space, it is recreated on next use
+		_ = os.RemoveAll(mirror.dir)
+		total -= mirror.size
+		delete(c.mirrors, key)
+	}
+}
+
+// openGitMirror opens the bare mirror in dir, initializing it if it is missing or unreadable
+// It reports whether the mirror was initialized
+func openGitMirror(dir, url string) (*git.Repository, bool, error) {
+	repo, err := git.PlainOpen(dir)
+	if err == nil {
+		if _, err := repo.Remote(git.DefaultRemoteName); err == nil {
+			return repo, false, nil
+		}
+	}
+
+	// Start over from an empty mirror
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, true, fmt.Errorf("failed to remove Git mirror: %w", err)
+	}
+	repo, err = git.PlainInit(dir, true)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to initialize Git mirror: %w", err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+		Fetch: []config.RefSpec{
+			"+refs/heads/*:refs/heads/*",
+			"+refs/tags/*:refs/tags/*",
+		},
+	})
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to create remote in Git mirror: %w", err)
+	}
+	return repo, true, nil
+}
+
+// extractGitPath writes the file or directory at path in the commit's tree into dir
+// and returns the local path to read configuration files from
+func extractGitPath(commit *object.Commit, path, dir string) (string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to read tree of commit %s: %w", commit.Hash, err)
+	}
+
+	treePath := strings.Trim(filepath.ToSlash(filepath.Clean(path)), "/")
+	if treePath != "." && treePath != "" {
+		entry, err := tree.FindEntry(treePath)
+		if err != nil {
+			return "", fmt.Errorf("path %s not found in commit %s", path, commit.Hash)
+		}
+
+		if entry.Mode != filemode.Dir {
+			// Single file, keep its base name as the key
+			file, err := tree.TreeEntryFile(entry)
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			filePath, err := gitFilePath(dir, entry.Name)
+			if err != nil {
+				return "", err
+			}
+			return filePath, writeGitFile(file, filePath)
+		}
+
+		tree, err = tree.Tree(treePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read directory %s: %w", path, err)
+		}
+	}
+
+	err = tree.Files().ForEach(func(file *object.File) error {
+		filePath, err := gitFilePath(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		return writeGitFile(file, filePath)
+	})
+	if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// gitFilePath returns the local path under dir for a file in a Git tree
+// Tree entries are not validated by Git, so names that would escape dir or write into a
+// .git directory are rejected
+func gitFilePath(dir, name string) (string, error) {
+	unsafe := func() error {
+		return &reasonError{reason: "UnsafeGitPath", err: fmt.Errorf("refusing to extract %q from Git tree", name)}
+	}
+	if name == "" || filepath.IsAbs(name) || strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return "", unsafe()
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." || part == ".." || strings.EqualFold(part, ".git") {
+			return "", unsafe()
+		}
+	}
+
+	filePath := filepath.Join(dir, filepath.FromSlash(name))
+	relative, err := filepath.Rel(dir, filePath)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", unsafe()
+	}
+	return filePath, nil
+}
+
+// writeGitFile writes the contents of a Git blob to filePath
+// Symlinks and submodules are skipped since they have no content of their own
+func writeGitFile(file *object.File, filePath string) error {
+	if file.Mode != filemode.Regular && file.Mode != filemode.Executable && file.Mode != filemode.Deprecated {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", file.Name, err)
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	defer reader.Close()
+
+	out, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filePath, err)
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	return out.Close()
+}
+
+// dirSize returns the total size of the files under dir
+func dirSize(dir string) int64 {
+	var size int64
+	// Walk errors only make the size an underestimate until the next fetch
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,268 @@
+// controllers/history.go
+
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+const (
+	// revisionOfLabel holds the UID of the source on the ConfigMaps storing its revisions
+	revisionOfLabel = "configmapsource.config.example.com/revision-of"
+	// revisionHashAnnotation holds the full hash of the content of a revision
+	revisionHashAnnotation = "configmapsource.config.example.com/hash"
+	// revisionPayloadKey is the binaryData key holding the gzipped content of a revision
+	revisionPayloadKey = "payload.json.gz"
+	// revisionNameLength is the number of hash characters in the name of a revision
+	revisionNameLength = 10
+	// defaultRevisionHistoryLimit is the number of revisions kept if not set
+	defaultRevisionHistoryLimit = 10
+)
+
+// revisionName returns the name of the revision of content with hash
+func revisionName(hash string) string {
+	return hash[:revisionNameLength]
+}
+
+// revisionNamespace returns the namespace the revisions of a source are stored in
+func revisionNamespace(configMapSource configv1alpha1.GenericConfigMapSource) (string, error) {
+	if configMapSource.GetNamespace() != "" {
+		return configMapSource.GetNamespace(), nil
+	}
+	if history := configMapSource.GetSpec().RevisionHistory; history != nil && history.Namespace != "" {
+		return history.Namespace, nil
+	}
+	return "", errMissingNamespace
+}
+
+// revisionConfigMapName returns the name of the ConfigMap storing a revision
+// The kind is part of the name, so sources of both kinds can store revisions in the same namespace
+func revisionConfigMapName(configMapSource configv1alpha1.GenericConfigMapSource, name string) string {
+	prefix := "configmapsource-" + configMapSource.GetName()
+	if configMapSource.GetNamespace() == "" {
+		prefix = "clusterconfigmapsource-" + configMapSource.GetName()
+	}
+	if len(prefix)+1+len(name) > validation.DNS1123SubdomainMaxLength {
+		sum := sha256.Sum256([]byte(prefix))
+		prefix = "configmapsource-" + hex.EncodeToString(sum[:16])
+	}
+	return prefix + "-" + name
+}
+
+// revisionHistoryLimit returns the number of revisions to keep
+func revisionHistoryLimit(configMapSource configv1alpha1.GenericConfigMapSource) int {
+	if limit := configMapSource.GetSpec().RevisionHistory.Limit; limit != nil && *limit > 0 {
+		return int(*limit)
+	}
+	return defaultRevisionHistoryLimit
+}
+
+// isRevisionOf checks if a ConfigMap stores a revision of the source
+func isRevisionOf(configMap *corev1.ConfigMap, configMapSource configv1alpha1.GenericConfigMapSource) bool {
+	return configMap.Labels[revisionOfLabel] == string(configMapSource.GetUID())
+}
+
+// recordRevision stores the synced content as the newest revision of the source,
+// and deletes the revisions beyond the history limit
+// Content synced before keeps its revision, which moves to the front of the history
+func (r *ConfigMapSourceReconciler) recordRevision(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, fetched *sourceData, hash string, syncTime metav1.Time) error {
+	logger := log.FromContext(ctx)
+
+	namespace, err := revisionNamespace(configMapSource)
+	if err != nil {
+		return err
+	}
+	name := revisionName(hash)
+	revisionConfigMapKey := types.NamespacedName{
+		Name:      revisionConfigMapName(configMapSource, name),
+		Namespace: namespace,
+	}
+
+	var existing corev1.ConfigMap
+	if err := r.Get(ctx, revisionConfigMapKey, &existing); err == nil {
+		if !isRevisionOf(&existing, configMapSource) {
+			return &reasonError{reason: "HistoryFailed", err: fmt.Errorf("ConfigMap %s already exists and is not a revision of this source", revisionConfigMapKey)}
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get revision ConfigMap %s: %w", revisionConfigMapKey, err)
+	} else {
+		payload, err := compressRevision(fetched)
+		if err != nil {
+			return err
+		}
+		revision := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        revisionConfigMapKey.Name,
+				Namespace:   namespace,
+				Labels:      map[string]string{revisionOfLabel: string(configMapSource.GetUID())},
+				Annotations: map[string]string{revisionHashAnnotation: hash},
+			},
+			BinaryData: map[string][]byte{revisionPayloadKey: payload},
+		}
+		// Revisions are always deleted with the source, whatever its deletion policy
+		if err := controllerutil.SetControllerReference(configMapSource, revision, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set owner reference on revision ConfigMap: %w", err)
+		}
+		logger.Info("Recording revision", "revision", name, "name", revisionConfigMapKey)
+		if err := r.Create(ctx, revision); err != nil {
+			return fmt.Errorf("failed to create revision ConfigMap %s: %w", revisionConfigMapKey, err)
+		}
+	}
+
+	history := []configv1alpha1.RevisionStatus{{
+		Name:           name,
+		Hash:           hash,
+		SourceRevision: fetched.revision,
+		SyncTime:       &syncTime,
+	}}
+	for _, revision := range configMapSource.GetStatus().History {
+		if revision.Hash != hash {
+			history = append(history, revision)
+		}
+	}
+	if limit := revisionHistoryLimit(configMapSource); len(history) > limit {
+		history = history[:limit]
+	}
+	configMapSource.GetStatus().History = history
+
+	return r.pruneRevisions(ctx, configMapSource, namespace)
+}
+
+// pruneRevisions deletes the revision ConfigMaps of the source that are no longer in its history
+func (r *ConfigMapSourceReconciler) pruneRevisions(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, namespace string) error {
+	logger := log.FromContext(ctx)
+
+	kept := make(map[string]bool)
+	for _, revision := range configMapSource.GetStatus().History {
+		kept[revisionConfigMapName(configMapSource, revision.Name)] = true
+	}
+
+	var revisions corev1.ConfigMapList
+	if err := r.List(ctx, &revisions, client.InNamespace(namespace), client.MatchingLabels{revisionOfLabel: string(configMapSource.GetUID())}); err != nil {
+		return fmt.Errorf("failed to list revision ConfigMaps: %w", err)
+	}
+	for i := range revisions.Items {
+		if kept[revisions.Items[i].Name] {
+			continue
+		}
+		logger.Info("Deleting revision beyond the history limit", "namespace", namespace, "name", revisions.Items[i].Name)
+		if err := r.Delete(ctx, &revisions.Items[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete revision ConfigMap %s/%s: %w", namespace, revisions.Items[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// loadRevision returns the content of a revision in the history of the source
+func (r *ConfigMapSourceReconciler) loadRevision(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, name string) (*sourceData, error) {
+	var entry *configv1alpha1.RevisionStatus
+	for i, revision := range configMapSource.GetStatus().History {
+		if revision.Name == name {
+			entry = &configMapSource.GetStatus().History[i]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, revisionNotFound(fmt.Sprintf("revision %s is not in status.history", name))
+	}
+
+	namespace, err := revisionNamespace(configMapSource)
+	if err != nil {
+		return nil, err
+	}
+	revisionConfigMapKey := types.NamespacedName{
+		Name:      revisionConfigMapName(configMapSource, name),
+		Namespace: namespace,
+	}
+	var revision corev1.ConfigMap
+	if err := r.Get(ctx, revisionConfigMapKey, &revision); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, revisionNotFound(fmt.Sprintf("content of revision %s is gone", name))
+		}
+		return nil, fmt.Errorf("failed to get revision ConfigMap %s: %w", revisionConfigMapKey, err)
+	}
+	if !isRevisionOf(&revision, configMapSource) {
+		return nil, revisionNotFound(fmt.Sprintf("ConfigMap %s is not a revision of this source", revisionConfigMapKey))
+	}
+
+	snapshot, err := decompressRevision(revision.BinaryData[revisionPayloadKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision %s: %w", name, err)
+	}
+	fetched := &sourceData{
+		data:       snapshot.Data,
+		binaryData: snapshot.BinaryData,
+		revision:   entry.SourceRevision,
+	}
+	if fetched.data == nil {
+		fetched.data = make(map[string]string)
+	}
+	if fetched.binaryData == nil {
+		fetched.binaryData = make(map[string][]byte)
+	}
+	return fetched, nil
+}
+
+// compressRevision returns the gzipped JSON of fetched content
+func compressRevision(fetched *sourceData) ([]byte, error) {
+	content, err := json.Marshal(targetSnapshot{
+		Data:       fetched.data,
+		BinaryData: fetched.binaryData,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode revision: %w", err)
+	}
+
+	var payload bytes.Buffer
+	writer := gzip.NewWriter(&payload)
+	if _, err := writer.Write(content); err != nil {
+		return nil, fmt.Errorf("failed to compress revision: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress revision: %w", err)
+	}
+	return payload.Bytes(), nil
+}
+
+// decompressRevision reads content written by compressRevision
+func decompressRevision(payload []byte) (*targetSnapshot, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot targetSnapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// revisionNotFound reports a pinned revision that cannot be synced
+func revisionNotFound(message string) error {
+	return &reasonError{reason: "RevisionNotFound", err: errors.New(message)}
+}
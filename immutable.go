@@ -0,0 +1,323 @@
+// controllers/immutable.go
+
+package controllers
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+const (
+	// immutableHashLength is the number of hash characters in the name of an immutable target
+	immutableHashLength = 10
+	// defaultImmutableHistoryLimit is the number of previous immutable targets kept if not set
+	defaultImmutableHistoryLimit = 3
+)
+
+// immutableTargetName returns the name of the immutable target ConfigMap for content with hash
+func immutableTargetName(configMapSource configv1alpha1.GenericConfigMapSource, hash string) string {
+	return configMapSource.GetSpec().TargetConfigMap + "-" + hash[:immutableHashLength]
+}
+
+// immutableHistoryLimit returns the number of previous immutable targets to keep
+func immutableHistoryLimit(configMapSource configv1alpha1.GenericConfigMapSource) int {
+	if limit := configMapSource.GetSpec().Immutable.HistoryLimit; limit != nil {
+		return int(*limit)
+	}
+	return defaultImmutableHistoryLimit
+}
+
+// isImmutableTarget checks if a ConfigMap is an immutable target written by the source
+func isImmutableTarget(configMap *corev1.ConfigMap, configMapSource configv1alpha1.GenericConfigMapSource) bool {
+	hash := strings.TrimPrefix(configMap.Name, configMapSource.GetSpec().TargetConfigMap+"-")
+	if hash == configMap.Name || len(hash) != immutableHashLength {
+		return false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return false
+	}
+	return configMap.Immutable != nil && *configMap.Immutable && isOwnedBy(configMap, configMapSource)
+}
+
+// syncImmutableTarget makes sure the immutable target ConfigMap for the desired content exists in namespace,
+// and garbage-collects previous ones beyond the history limit
+// The content of an immutable target cannot change, so only its deletion counts as drift
+func (r *ConfigMapSourceReconciler) syncImmutableTarget(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, namespace string, desired *desiredTarget, sync bool, driftPolicy string) (targetResult, error) {
+	logger := log.FromContext(ctx)
+	var result targetResult
+
+	targetConfigMapName := types.NamespacedName{
+		Name:      immutableTargetName(configMapSource, desired.hash),
+		Namespace: namespace,
+	}
+	if len(targetConfigMapName.Name) > validation.DNS1123SubdomainMaxLength {
+		return result, &reasonError{reason: "InvalidTargetName", err: fmt.Errorf("name of immutable ConfigMap %s is longer than %d characters", targetConfigMapName.Name, validation.DNS1123SubdomainMaxLength)}
+	}
+
+	var targetConfigMap corev1.ConfigMap
+	configMapExists := true
+	if err := r.Get(ctx, targetConfigMapName, &targetConfigMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return result, fmt.Errorf("failed to get target ConfigMap: %w", err)
+		}
+		configMapExists = false
+	}
+	if configMapExists && !isImmutableTarget(&targetConfigMap, configMapSource) {
+		return result, &reasonError{reason: "TargetConflict", err: fmt.Errorf("ConfigMap %s already exists and is not an immutable target of this source", targetConfigMapName)}
+	}
+
+	if !sync && driftPolicy != "Ignore" {
+		result.drifted = !configMapExists
+		if result.drifted {
+			logger.Info("Immutable target ConfigMap was deleted", "name", targetConfigMapName, "driftPolicy", driftPolicy)
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, "DriftDetected", "Immutable target ConfigMap %s was deleted", targetConfigMapName)
+		}
+	}
+	if configMapExists || (!sync && (!result.drifted || driftPolicy != "Correct")) {
+		return result, r.collectImmutableTargets(ctx, configMapSource, namespace, targetConfigMapName.Name)
+	}
+
+	// Create the immutable target, owned like an in-place target
+	logger.Info("Creating immutable ConfigMap", "name", targetConfigMapName)
+	immutable := true
+	created := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      targetConfigMapName.Name,
+			Namespace: namespace,
+		},
+		Data:       desired.data,
+		BinaryData: desired.binaryData,
+		Immutable:  &immutable,
+	}
+	if configMapSource.GetNamespace() == namespace {
+		if err := controllerutil.SetControllerReference(configMapSource, created, r.Scheme); err != nil {
+			return result, fmt.Errorf("failed to set owner reference on ConfigMap: %w", err)
+		}
+	} else {
+		gvk, err := apiutil.GVKForObject(configMapSource, r.Scheme)
+		if err != nil {
+			return result, fmt.Errorf("failed to get kind of source: %w", err)
+		}
+		setOwnerLabels(created, configMapSource, gvk.Kind)
+	}
+	if err := r.Create(ctx, created); err != nil {
+		return result, fmt.Errorf("failed to create immutable ConfigMap %s: %w", targetConfigMapName, err)
+	}
+	result.applied = true
+	targetBytesWritten.WithLabelValues(sourceTypeLabel(configMapSource)).Add(float64(desired.size()))
+
+	// Workloads keep mounting the ConfigMap they reference, so the new one replaces nothing
+	// and restarting them would bring back the same content
+
+	if result.drifted {
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "DriftCorrected", "Recreated immutable target ConfigMap %s", targetConfigMapName)
+	} else {
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "TargetCreated", "Created immutable target ConfigMap %s", targetConfigMapName)
+	}
+
+	return result, r.collectImmutableTargets(ctx, configMapSource, namespace, targetConfigMapName.Name)
+}
+
+// collectImmutableTargets deletes the immutable targets in namespace older than the history limit
+// that no pod or workload references, the latest one is always kept
+func (r *ConfigMapSourceReconciler) collectImmutableTargets(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, namespace, latest string) error {
+	logger := log.FromContext(ctx)
+
+	var configMaps corev1.ConfigMapList
+	if err := r.List(ctx, &configMaps, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list ConfigMaps in namespace %s: %w", namespace, err)
+	}
+	var previous []*corev1.ConfigMap
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if configMap.Name != latest && isImmutableTarget(configMap, configMapSource) {
+			previous = append(previous, configMap)
+		}
+	}
+
+	limit := immutableHistoryLimit(configMapSource)
+	if len(previous) <= limit {
+		return nil
+	}
+
+	// Newest first, so the ones beyond the limit are the oldest
+	sort.Slice(previous, func(i, j int) bool {
+		if !previous[i].CreationTimestamp.Equal(&previous[j].CreationTimestamp) {
+			return previous[j].CreationTimestamp.Before(&previous[i].CreationTimestamp)
+		}
+		return previous[i].Name < previous[j].Name
+	})
+
+	inUse, err := r.configMapsInUse(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for _, configMap := range previous[limit:] {
+		if inUse[configMap.Name] {
+			logger.Info("Keeping immutable ConfigMap referenced by pods or workloads", "namespace", namespace, "name", configMap.Name)
+			continue
+		}
+		logger.Info("Deleting immutable ConfigMap beyond the history limit", "namespace", namespace, "name", configMap.Name)
+		if err := r.Delete(ctx, configMap); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete immutable ConfigMap %s/%s: %w", namespace, configMap.Name, err)
+		}
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "TargetPruned", "Deleted immutable ConfigMap %s/%s beyond the history limit", namespace, configMap.Name)
+	}
+	return nil
+}
+
+// configMapsInUse returns the names of the ConfigMaps referenced in namespace by running pods,
+// or by the pod templates of workloads that may create pods
+// ReplicaSets are only checked while they have replicas, so an old revision of a Deployment
+// doesn't keep its ConfigMaps
+func (r *ConfigMapSourceReconciler) configMapsInUse(ctx context.Context, namespace string) (map[string]bool, error) {
+	inUse := make(map[string]bool)
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list pods in namespace %s: %w", namespace, err)
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodSucceeded || pods.Items[i].Status.Phase == corev1.PodFailed {
+			continue
+		}
+		addConfigMapsInUse(inUse, &pods.Items[i].Spec)
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := r.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Deployments in namespace %s: %w", namespace, err)
+	}
+	for i := range deployments.Items {
+		addConfigMapsInUse(inUse, &deployments.Items[i].Spec.Template.Spec)
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := r.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list StatefulSets in namespace %s: %w", namespace, err)
+	}
+	for i := range statefulSets.Items {
+		addConfigMapsInUse(inUse, &statefulSets.Items[i].Spec.Template.Spec)
+	}
+
+	var daemonSets appsv1.DaemonSetList
+	if err := r.List(ctx, &daemonSets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list DaemonSets in namespace %s: %w", namespace, err)
+	}
+	for i := range daemonSets.Items {
+		addConfigMapsInUse(inUse, &daemonSets.Items[i].Spec.Template.Spec)
+	}
+
+	var replicaSets appsv1.ReplicaSetList
+	if err := r.List(ctx, &replicaSets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ReplicaSets in namespace %s: %w", namespace, err)
+	}
+	for i := range replicaSets.Items {
+		replicaSet := &replicaSets.Items[i]
+		if replicaSet.Status.Replicas == 0 && (replicaSet.Spec.Replicas == nil || *replicaSet.Spec.Replicas == 0) {
+			continue
+		}
+		addConfigMapsInUse(inUse, &replicaSet.Spec.Template.Spec)
+	}
+	return inUse, nil
+}
+
+// addConfigMapsInUse adds the names of the ConfigMaps referenced by a pod spec to inUse
+func addConfigMapsInUse(inUse map[string]bool, spec *corev1.PodSpec) {
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil {
+			inUse[volume.ConfigMap.Name] = true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					inUse[source.ConfigMap.Name] = true
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range spec.EphemeralContainers {
+		containers = append(containers, corev1.Container(container.EphemeralContainerCommon))
+	}
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				inUse[env.ValueFrom.ConfigMapKeyRef.Name] = true
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				inUse[envFrom.ConfigMapRef.Name] = true
+			}
+		}
+	}
+}
+
+// releaseImmutableTargets applies the deletion policy of the source to all its immutable targets in namespace
+func (r *ConfigMapSourceReconciler) releaseImmutableTargets(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, namespace string) error {
+	var configMaps corev1.ConfigMapList
+	if err := r.List(ctx, &configMaps, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list ConfigMaps in namespace %s: %w", namespace, err)
+	}
+	for i := range configMaps.Items {
+		if !isImmutableTarget(&configMaps.Items[i], configMapSource) {
+			continue
+		}
+		if err := r.releaseTarget(ctx, configMapSource, &configMaps.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseStaleImmutableTargets releases the immutable targets left in namespace after spec.immutable was unset
+// Those still referenced by pods or workloads are kept until a later sync finds them unused
+func (r *ConfigMapSourceReconciler) releaseStaleImmutableTargets(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, namespace string) error {
+	logger := log.FromContext(ctx)
+
+	var configMaps corev1.ConfigMapList
+	if err := r.List(ctx, &configMaps, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list ConfigMaps in namespace %s: %w", namespace, err)
+	}
+	var stale []*corev1.ConfigMap
+	for i := range configMaps.Items {
+		if isImmutableTarget(&configMaps.Items[i], configMapSource) {
+			stale = append(stale, &configMaps.Items[i])
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	inUse, err := r.configMapsInUse(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	for _, configMap := range stale {
+		if inUse[configMap.Name] {
+			logger.Info("Keeping immutable ConfigMap referenced by pods or workloads", "namespace", namespace, "name", configMap.Name)
+			continue
+		}
+		if err := r.releaseTarget(ctx, configMapSource, configMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
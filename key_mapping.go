@@ -0,0 +1,116 @@
+// controllers/key_mapping.go
+
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+// defaultKeySeparator joins path segments when flattening relative paths into keys
+const defaultKeySeparator = "__"
+
+// keyMapper turns file paths relative to a source path into ConfigMap keys
+type keyMapper struct {
+	strategy  string
+	separator string
+	template  *template.Template
+}
+
+// keyTemplateData is passed to KeyMapping templates
+type keyTemplateData struct {
+	Path string
+	Dir  string
+	Base string
+	Name string
+	Ext  string
+}
+
+// keyTemplateFuncs are the functions available to KeyMapping templates
+// They take the string to operate on last so they can be used in pipelines
+var keyTemplateFuncs = template.FuncMap{
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+}
+
+// newKeyMapper creates a keyMapper from a KeyMapping, which may be nil
+func newKeyMapper(mapping *configv1alpha1.KeyMapping) (*keyMapper, error) {
+	mapper := &keyMapper{
+		strategy:  "Flatten",
+		separator: defaultKeySeparator,
+	}
+	if mapping == nil {
+		return mapper, nil
+	}
+
+	if mapping.Strategy != "" {
+		mapper.strategy = mapping.Strategy
+	}
+	if mapping.Separator != "" {
+		mapper.separator = mapping.Separator
+	}
+
+	switch mapper.strategy {
+	case "Flatten", "Basename":
+	case "Template":
+		if mapping.Template == "" {
+			return nil, invalidKeyMapping("keyMapping.template is required with the Template strategy")
+		}
+		tmpl, err := template.New("key").Funcs(keyTemplateFuncs).Option("missingkey=error").Parse(mapping.Template)
+		if err != nil {
+			return nil, invalidKeyMapping(fmt.Sprintf("failed to parse keyMapping.template: %v", err))
+		}
+		mapper.template = tmpl
+	default:
+		return nil, invalidKeyMapping(fmt.Sprintf("unsupported keyMapping.strategy: %s", mapper.strategy))
+	}
+
+	return mapper, nil
+}
+
+// key returns the ConfigMap key for a slash-separated path relative to the source path
+func (m *keyMapper) key(relPath string) (string, error) {
+	var key string
+	switch m.strategy {
+	case "Basename":
+		key = path.Base(relPath)
+	case "Template":
+		base := path.Base(relPath)
+		ext := path.Ext(base)
+		data := keyTemplateData{
+			Path: relPath,
+			Dir:  path.Dir(relPath),
+			Base: base,
+			Name: strings.TrimSuffix(base, ext),
+			Ext:  ext,
+		}
+		var out bytes.Buffer
+		if err := m.template.Execute(&out, data); err != nil {
+			return "", invalidKeyMapping(fmt.Sprintf("failed to render key for %s: %v", relPath, err))
+		}
+		key = strings.TrimSpace(out.String())
+	default:
+		key = strings.ReplaceAll(relPath, "/", m.separator)
+	}
+
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return "", invalidKeyMapping(fmt.Sprintf("file %s maps to invalid key %q: %s", relPath, key, strings.Join(errs, ", ")))
+	}
+	return key, nil
+}
+
+// invalidKeyMapping reports file paths that cannot be mapped to ConfigMap keys
+func invalidKeyMapping(message string) error {
+	return &reasonError{reason: "InvalidKeyMapping", err: errors.New(message)}
+}
@@ -0,0 +1,206 @@
+// controllers/metrics.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+// multipleSourcesLabel is the source_type of sources using spec.sources
+const multipleSourcesLabel = "Multiple"
+
+var (
+	syncAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "configmapsource_sync_attempts_total",
+		Help: "Number of syncs attempted, by source type",
+	}, []string{"source_type"})
+
+	syncSuccesses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "configmapsource_sync_successes_total",
+		Help: "Number of syncs that succeeded, by source type",
+	}, []string{"source_type"})
+
+	syncFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "configmapsource_sync_failures_total",
+		Help: "Number of syncs that failed, by source type and Ready condition reason",
+	}, []string{"source_type", "reason"})
+
+	fetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "configmapsource_fetch_duration_seconds",
+		Help:    "Time taken to fetch configuration data from a source, by source type",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"source_type"})
+
+	gitFetchDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "configmapsource_git_fetch_duration_seconds",
+		Help:    "Time taken to clone or fetch a Git repository into its mirror",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 14),
+	})
+
+	gitFetchBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "configmapsource_git_fetch_bytes_total",
+		Help: "Number of bytes Git mirrors grew by when cloned or fetched",
+	})
+
+	targetBytesWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "configmapsource_target_bytes_written_total",
+		Help: "Number of bytes of data applied to target ConfigMaps, by source type",
+	}, []string{"source_type"})
+
+	sourceStates = newSourceStateCollector()
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		syncAttempts,
+		syncSuccesses,
+		syncFailures,
+		fetchDuration,
+		gitFetchDuration,
+		gitFetchBytes,
+		targetBytesWritten,
+		sourceStates,
+	)
+}
+
+// sourceTypeLabel returns the source_type label of a ConfigMapSource
+func sourceTypeLabel(configMapSource configv1alpha1.GenericConfigMapSource) string {
+	if len(configMapSource.GetSpec().Sources) > 0 {
+		return multipleSourcesLabel
+	}
+	return configMapSource.GetSpec().SourceType
+}
+
+// sourceKind returns the kind of a ConfigMapSource without a scheme lookup
+func sourceKind(configMapSource configv1alpha1.GenericConfigMapSource) string {
+	if _, ok := configMapSource.(*configv1alpha1.ClusterConfigMapSource); ok {
+		return "ClusterConfigMapSource"
+	}
+	return "ConfigMapSource"
+}
+
+// observeFetchDuration records the time since start as a fetch of sourceType
+func observeFetchDuration(sourceType string, start time.Time) {
+	fetchDuration.WithLabelValues(sourceType).Observe(time.Since(start).Seconds())
+}
+
+// sourceState is the last reconciled state of a ConfigMapSource
+type sourceState struct {
+	kind, namespace, name string
+	lastSyncTime          time.Time
+	ready                 string
+}
+
+// sourceStateCollector reports the time since the last successful sync of each
+// ConfigMapSource and the number of ConfigMapSources by Ready status
+// Both are computed when scraped, from the state recorded with each status write
+type sourceStateCollector struct {
+	sinceLastSync *prometheus.Desc
+	ready         *prometheus.Desc
+
+	mu     sync.Mutex
+	states map[types.UID]sourceState
+}
+
+// newSourceStateCollector creates an empty sourceStateCollector
+func newSourceStateCollector() *sourceStateCollector {
+	return &sourceStateCollector{
+		sinceLastSync: prometheus.NewDesc("configmapsource_seconds_since_last_successful_sync",
+			"Seconds since the last successful sync of a ConfigMapSource",
+			[]string{"kind", "namespace", "name"}, nil),
+		ready: prometheus.NewDesc("configmapsource_sources",
+			"Number of ConfigMapSources by status of their Ready condition",
+			[]string{"ready"}, nil),
+		states: make(map[types.UID]sourceState),
+	}
+}
+
+// observe records the state of a ConfigMapSource
+func (c *sourceStateCollector) observe(configMapSource configv1alpha1.GenericConfigMapSource) {
+	state := sourceState{
+		kind:      sourceKind(configMapSource),
+		namespace: configMapSource.GetNamespace(),
+		name:      configMapSource.GetName(),
+		ready:     "Unknown",
+	}
+	if lastSyncTime := configMapSource.GetStatus().LastSyncTime; lastSyncTime != nil {
+		state.lastSyncTime = lastSyncTime.Time
+	}
+	if ready := meta.FindStatusCondition(configMapSource.GetStatus().Conditions, "Ready"); ready != nil {
+		state.ready = string(ready.Status)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states[configMapSource.GetUID()] = state
+}
+
+// forget drops the state of a deleted ConfigMapSource
+func (c *sourceStateCollector) forget(configMapSource configv1alpha1.GenericConfigMapSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.states, configMapSource.GetUID())
+}
+
+// sourceStateSeeder records the state of the sources in a list once the manager is elected leader,
+// so the collector reports sources that are not reconciled for a while after a restart
+type sourceStateSeeder struct {
+	reader  client.Reader
+	newList func() client.ObjectList
+}
+
+// Start lists the sources and records their state
+func (s *sourceStateSeeder) Start(ctx context.Context) error {
+	list := s.newList()
+	if err := s.reader.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list sources for metrics: %w", err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if configMapSource, ok := item.(configv1alpha1.GenericConfigMapSource); ok {
+			sourceStates.observe(configMapSource)
+		}
+	}
+	return nil
+}
+
+// Describe implements prometheus.Collector
+func (c *sourceStateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sinceLastSync
+	ch <- c.ready
+}
+
+// Collect implements prometheus.Collector
+func (c *sourceStateCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	counts := map[string]int{"True": 0, "False": 0, "Unknown": 0}
+	for _, state := range c.states {
+		counts[state.ready]++
+		// Sources that never synced have no last sync to measure from
+		if state.lastSyncTime.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.sinceLastSync, prometheus.GaugeValue,
+			now.Sub(state.lastSyncTime).Seconds(), state.kind, state.namespace, state.name)
+	}
+	for ready, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.ready, prometheus.GaugeValue, float64(count), ready)
+	}
+}
@@ -0,0 +1,213 @@
+// controllers/rollout.go
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+const (
+	// configHashAnnotation is set on the pod template of rollout targets to the hash of the synced content,
+	// so changing it makes the workload controller roll out new pods
+	configHashAnnotation = "configmapsource.config.example.com/config-hash"
+	// restartedAtAnnotation is set on the pod template of rollout targets restarted for content
+	// whose hash they already carry, e.g. after a drift correction
+	restartedAtAnnotation = "configmapsource.config.example.com/restarted-at"
+)
+
+// rolloutWorkloads restarts the workloads selected by the rollout targets of a ConfigMapSource
+// in the namespaces whose target content was replaced with content with hash, and returns the workloads
+// that carry the hash
+// Workloads already annotated with the hash are left alone, so a retry after a failure
+// only restarts the rest, unless restartedAt is set to restart them anyway
+func (r *ConfigMapSourceReconciler) rolloutWorkloads(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, targetNamespaces []string, replaced map[string]bool, hash, restartedAt string) ([]configv1alpha1.WorkloadReference, error) {
+	logger := log.FromContext(ctx)
+
+	var workloads []configv1alpha1.WorkloadReference
+	var rolloutErr error
+	for _, rolloutTarget := range configMapSource.GetSpec().RolloutTargets {
+		namespaces, err := rolloutNamespaces(&rolloutTarget, targetNamespaces)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, namespace := range namespaces {
+			if !replaced[namespace] {
+				continue
+			}
+			selected, err := r.selectWorkloads(ctx, &rolloutTarget, namespace)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, workload := range selected {
+				reference := configv1alpha1.WorkloadReference{
+					Kind:      rolloutTarget.Kind,
+					Namespace: workload.GetNamespace(),
+					Name:      workload.GetName(),
+				}
+				restarted, err := r.restartWorkload(ctx, workload, hash, restartedAt)
+				if err != nil {
+					logger.Error(err, "Failed to restart workload", "kind", reference.Kind, "namespace", reference.Namespace, "name", reference.Name)
+					if rolloutErr == nil {
+						rolloutErr = fmt.Errorf("failed to restart %s %s/%s: %w", reference.Kind, reference.Namespace, reference.Name, err)
+					}
+					continue
+				}
+				if restarted {
+					logger.Info("Restarted workload for new configuration", "kind", reference.Kind, "namespace", reference.Namespace, "name", reference.Name)
+					r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "RolloutTriggered", "Restarted %s %s/%s for new configuration", reference.Kind, reference.Namespace, reference.Name)
+				}
+				workloads = append(workloads, reference)
+			}
+		}
+	}
+	if rolloutErr != nil {
+		return nil, &reasonError{reason: "RolloutFailed", err: rolloutErr}
+	}
+	return workloads, nil
+}
+
+// rolloutNamespaces returns the namespaces to look up the workloads of a rollout target in
+// Workloads can only be restarted in target namespaces, where they can consume the target ConfigMap
+func rolloutNamespaces(rolloutTarget *configv1alpha1.RolloutTarget, targetNamespaces []string) ([]string, error) {
+	if (rolloutTarget.Name == "") == (rolloutTarget.Selector == nil) {
+		return nil, invalidRolloutTarget(fmt.Sprintf("exactly one of name and selector must be set for %s rollout target", rolloutTarget.Kind))
+	}
+	if rolloutTarget.Namespace == "" {
+		return targetNamespaces, nil
+	}
+	for _, namespace := range targetNamespaces {
+		if namespace == rolloutTarget.Namespace {
+			return []string{namespace}, nil
+		}
+	}
+	return nil, invalidRolloutTarget(fmt.Sprintf("rollout target namespace %s is not a target namespace", rolloutTarget.Namespace))
+}
+
+// selectWorkloads returns the workloads selected by a rollout target in namespace
+// A workload selected by name that doesn't exist is skipped
+func (r *ConfigMapSourceReconciler) selectWorkloads(ctx context.Context, rolloutTarget *configv1alpha1.RolloutTarget, namespace string) ([]client.Object, error) {
+	if rolloutTarget.Name != "" {
+		workload, err := newWorkload(rolloutTarget.Kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: rolloutTarget.Name}, workload); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to get %s %s/%s: %w", rolloutTarget.Kind, namespace, rolloutTarget.Name, err)
+		}
+		return []client.Object{workload}, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(rolloutTarget.Selector)
+	if err != nil {
+		return nil, invalidRolloutTarget(fmt.Sprintf("invalid selector for %s rollout target: %v", rolloutTarget.Kind, err))
+	}
+	list, err := newWorkloadList(rolloutTarget.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.List(ctx, list, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list %s in namespace %s: %w", rolloutTarget.Kind, namespace, err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	workloads := make([]client.Object, 0, len(items))
+	for _, item := range items {
+		if workload, ok := item.(client.Object); ok {
+			workloads = append(workloads, workload)
+		}
+	}
+	return workloads, nil
+}
+
+// restartWorkload sets the config hash annotation on the pod template of a workload,
+// and the restart time if restartedAt is set
+// It reports whether the annotations changed, which triggers a rollout
+func (r *ConfigMapSourceReconciler) restartWorkload(ctx context.Context, workload client.Object, hash, restartedAt string) (bool, error) {
+	template := podTemplate(workload)
+	if template == nil {
+		return false, fmt.Errorf("unsupported workload type %T", workload)
+	}
+	if template.Annotations[configHashAnnotation] == hash && (restartedAt == "" || template.Annotations[restartedAtAnnotation] == restartedAt) {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations[configHashAnnotation] = hash
+	if restartedAt != "" {
+		template.Annotations[restartedAtAnnotation] = restartedAt
+	}
+	if err := r.Patch(ctx, workload, patch); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// newWorkload returns an empty workload of kind
+func newWorkload(kind string) (client.Object, error) {
+	switch kind {
+	case "Deployment":
+		return &appsv1.Deployment{}, nil
+	case "StatefulSet":
+		return &appsv1.StatefulSet{}, nil
+	case "DaemonSet":
+		return &appsv1.DaemonSet{}, nil
+	default:
+		return nil, invalidRolloutTarget(fmt.Sprintf("unsupported rollout target kind: %s", kind))
+	}
+}
+
+// newWorkloadList returns an empty list of workloads of kind
+func newWorkloadList(kind string) (client.ObjectList, error) {
+	switch kind {
+	case "Deployment":
+		return &appsv1.DeploymentList{}, nil
+	case "StatefulSet":
+		return &appsv1.StatefulSetList{}, nil
+	case "DaemonSet":
+		return &appsv1.DaemonSetList{}, nil
+	default:
+		return nil, invalidRolloutTarget(fmt.Sprintf("unsupported rollout target kind: %s", kind))
+	}
+}
+
+// podTemplate returns the pod template of a workload, nil for unsupported types
+func podTemplate(workload client.Object) *corev1.PodTemplateSpec {
+	switch workload := workload.(type) {
+	case *appsv1.Deployment:
+		return &workload.Spec.Template
+	case *appsv1.StatefulSet:
+		return &workload.Spec.Template
+	case *appsv1.DaemonSet:
+		return &workload.Spec.Template
+	default:
+		return nil
+	}
+}
+
+// invalidRolloutTarget reports a rollout target that cannot be used
+func invalidRolloutTarget(message string) error {
+	return &reasonError{reason: "InvalidRolloutTarget", err: errors.New(message)}
+}
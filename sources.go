@@ -0,0 +1,108 @@
+// controllers/sources.go
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+// specSources returns the sources of a ConfigMapSource in order of precedence
+// The single-source fields are returned as a list of one
+func specSources(configMapSource configv1alpha1.GenericConfigMapSource) []configv1alpha1.SourceSpec {
+	if len(configMapSource.GetSpec().Sources) > 0 {
+		return configMapSource.GetSpec().Sources
+	}
+	return []configv1alpha1.SourceSpec{{
+		Type:      configMapSource.GetSpec().SourceType,
+		Git:       configMapSource.GetSpec().Git,
+		File:      configMapSource.GetSpec().File,
+		ConfigMap: configMapSource.GetSpec().ConfigMap,
+		Secret:    configMapSource.GetSpec().Secret,
+	}}
+}
+
+// sourceName returns the name of the source at index in spec.sources
+func sourceName(source *configv1alpha1.SourceSpec, index int) string {
+	if source.Name != "" {
+		return source.Name
+	}
+	return fmt.Sprintf("%s-%d", strings.ToLower(source.Type), index)
+}
+
+// fetchSources retrieves configuration data from each entry of spec.sources and merges it
+// Later sources take precedence over earlier ones for keys they both provide
+func (r *ConfigMapSourceReconciler) fetchSources(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource) (*sourceData, error) {
+	logger := log.FromContext(ctx)
+
+	merged := &sourceData{
+		data:       make(map[string]string),
+		binaryData: make(map[string][]byte),
+	}
+	providers := make(map[string][]string)
+	seen := make(map[string]bool)
+
+	for i := range configMapSource.GetSpec().Sources {
+		source := &configMapSource.GetSpec().Sources[i]
+		name := sourceName(source, i)
+		if seen[name] {
+			return nil, invalidSources(fmt.Sprintf("duplicate source name %s", name))
+		}
+		seen[name] = true
+
+		// Start from the state of the last sync, so unchanged Git sources skip the fetch
+		status := configv1alpha1.SourceStatus{Name: name}
+		for _, previous := range configMapSource.GetStatus().Sources {
+			if previous.Name == name {
+				status.Revision = previous.Revision
+				status.GitPoll = previous.GitPoll
+				break
+			}
+		}
+
+		fetched, err := r.fetchSource(ctx, configMapSource, source, &status)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", name, err)
+		}
+		status.Revision = fetched.revision
+		merged.sources = append(merged.sources, status)
+
+		// A key can move between data and binaryData from one source to the next
+		for key, value := range fetched.data {
+			delete(merged.binaryData, key)
+			merged.data[key] = value
+			providers[key] = append(providers[key], name)
+		}
+		for key, value := range fetched.binaryData {
+			delete(merged.data, key)
+			merged.binaryData[key] = value
+			providers[key] = append(providers[key], name)
+		}
+	}
+
+	for key, names := range providers {
+		if len(names) > 1 {
+			merged.conflicts = append(merged.conflicts, configv1alpha1.KeyConflict{Key: key, Sources: names})
+		}
+	}
+	sort.Slice(merged.conflicts, func(i, j int) bool {
+		return merged.conflicts[i].Key < merged.conflicts[j].Key
+	})
+	if len(merged.conflicts) > 0 {
+		logger.Info("Keys provided by more than one source, the last source wins", "conflicts", len(merged.conflicts))
+	}
+
+	return merged, nil
+}
+
+// invalidSources reports a spec whose sources cannot be used
+func invalidSources(message string) error {
+	return &reasonError{reason: "InvalidSources", err: errors.New(message)}
+}
@@ -0,0 +1,117 @@
+// controllers/substitution.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+// variablePattern matches ${NAME} placeholders, and $${NAME} escapes
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// substituter replaces the placeholders in the keys selected by spec.substitution
+type substituter struct {
+	include   []string
+	variables map[string]string
+	strict    bool
+}
+
+// newSubstituter loads the variables of spec.substitution, it returns nil if substitution is not enabled
+func (r *ConfigMapSourceReconciler) newSubstituter(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource) (*substituter, error) {
+	substitution := configMapSource.GetSpec().Substitution
+	if substitution == nil {
+		return nil, nil
+	}
+	for _, pattern := range substitution.Include {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, &reasonError{reason: "InvalidFilePattern", err: fmt.Errorf("invalid glob pattern: %s", pattern)}
+		}
+	}
+
+	variables, err := r.loadValues(ctx, configMapSource, substitution.VariablesFrom)
+	if err != nil {
+		return nil, err
+	}
+	return &substituter{
+		include:   substitution.Include,
+		variables: variables,
+		strict:    substitution.Strict,
+	}, nil
+}
+
+// hash combines the hash of the content with the variables,
+// so a change to the variables is synced like a change to the content
+func (s *substituter) hash(contentHash string) string {
+	inputs := map[string]string{
+		"content": contentHash,
+		"include": strings.Join(s.include, "\n"),
+		"strict":  strconv.FormatBool(s.strict),
+	}
+	for name, value := range s.variables {
+		inputs["variables/"+name] = value
+	}
+	return calculateConfigHash(inputs, nil)
+}
+
+// apply returns a copy of desired with the placeholders replaced by the variables
+// In strict mode placeholders without a variable fail with the keys they are in
+func (s *substituter) apply(desired *desiredTarget) (*desiredTarget, error) {
+	substituted := *desired
+	substituted.substituter = nil
+	substituted.data = make(map[string]string, len(desired.data))
+
+	unresolved := make(map[string][]string)
+	for key, value := range desired.data {
+		if len(s.include) > 0 && !matchesAny(key, s.include) {
+			substituted.data[key] = value
+			continue
+		}
+		var missing []string
+		substituted.data[key], missing = substitute(value, s.variables)
+		for _, name := range missing {
+			unresolved[name] = append(unresolved[name], key)
+		}
+	}
+
+	if !s.strict || len(unresolved) == 0 {
+		return &substituted, nil
+	}
+	names := make([]string, 0, len(unresolved))
+	for name, keys := range unresolved {
+		sort.Strings(keys)
+		names = append(names, fmt.Sprintf("%s (%s)", name, strings.Join(keys, ", ")))
+	}
+	sort.Strings(names)
+	return nil, &reasonError{reason: "UnresolvedVariables", err: fmt.Errorf("no variable for placeholders: %s", strings.Join(names, ", "))}
+}
+
+// substitute replaces the placeholders in content with variables, and returns the names
+// of the placeholders left unresolved
+func substitute(content string, variables map[string]string) (string, []string) {
+	var unresolved []string
+	seen := make(map[string]bool)
+	substituted := variablePattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		if strings.HasPrefix(placeholder, "$$") {
+			return placeholder[1:]
+		}
+		name := placeholder[2 : len(placeholder)-1]
+		if value, ok := variables[name]; ok {
+			return value
+		}
+		if !seen[name] {
+			seen[name] = true
+			unresolved = append(unresolved, name)
+		}
+		return placeholder
+	})
+	return substituted, unresolved
+}
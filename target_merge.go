@@ -0,0 +1,242 @@
+// controllers/target_merge.go
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+// fieldManagerPrefix prefixes the field managers used for server-side apply of target ConfigMaps
+const fieldManagerPrefix = "configmapsource"
+
+// legacyFieldManager is the field manager the API server recorded for the plain updates of
+// versions of the operator from before server-side apply, derived from the user agent of the client
+var legacyFieldManager = strings.SplitN(rest.DefaultKubernetesUserAgent(), "/", 2)[0]
+
+// desiredTarget is the content a ConfigMapSource wants in its target ConfigMap
+type desiredTarget struct {
+	strategy   string
+	data       map[string]string
+	binaryData map[string][]byte
+	// hash is the config hash of the content
+	hash string
+	// renderer renders the content for each target namespace, nil if templating is not enabled
+	renderer *templateRenderer
+	// substituter replaces the placeholders in the rendered content, nil if substitution is not enabled
+	substituter *substituter
+}
+
+// replacing reports whether the target should hold exactly the source keys
+func (d *desiredTarget) replacing() bool {
+	return d.strategy == "" || d.strategy == "Replace"
+}
+
+// matches checks if the target ConfigMap holds the desired content
+// Keys written by others only count as drift with the Replace strategy
+func (d *desiredTarget) matches(target *corev1.ConfigMap) bool {
+	for key, value := range d.data {
+		if targetValue, exists := target.Data[key]; !exists || targetValue != value {
+			return false
+		}
+	}
+	for key, value := range d.binaryData {
+		if targetValue, exists := target.BinaryData[key]; !exists || string(targetValue) != string(value) {
+			return false
+		}
+	}
+	return !d.replacing() || len(d.extraKeys(target)) == 0
+}
+
+// size returns the number of bytes of desired content
+func (d *desiredTarget) size() int {
+	size := 0
+	for key, value := range d.data {
+		size += len(key) + len(value)
+	}
+	for key, value := range d.binaryData {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+// extraKeys returns the keys of the target ConfigMap that are not in the desired content
+func (d *desiredTarget) extraKeys(target *corev1.ConfigMap) []string {
+	var extra []string
+	for key := range target.Data {
+		if _, exists := d.data[key]; !exists {
+			extra = append(extra, key)
+		}
+	}
+	for key := range target.BinaryData {
+		if _, exists := d.binaryData[key]; !exists {
+			extra = append(extra, key)
+		}
+	}
+	return extra
+}
+
+// targetFieldManager returns the field manager a ConfigMapSource applies its target with
+// Each ConfigMapSource has its own, so the API server tracks which keys each one owns
+func targetFieldManager(configMapSource configv1alpha1.GenericConfigMapSource) string {
+	fieldManager := fmt.Sprintf("%s/%s/%s", fieldManagerPrefix, configMapSource.GetNamespace(), configMapSource.GetName())
+	if configMapSource.GetNamespace() == "" {
+		fieldManager = fmt.Sprintf("cluster%s/%s", fieldManagerPrefix, configMapSource.GetName())
+	}
+	// Field managers are limited to 128 characters
+	if len(fieldManager) > 128 {
+		sum := sha256.Sum256([]byte(fieldManager))
+		fieldManager = fmt.Sprintf("%s/%s", fieldManagerPrefix, hex.EncodeToString(sum[:16]))
+	}
+	return fieldManager
+}
+
+// applyTarget writes the desired content to the target ConfigMap with server-side apply
+// target is the live ConfigMap, or the initialized one if it does not exist yet
+// With MergeFailOnConflict the apply is not forced, so keys owned by another field
+// manager with a different value fail with a Conflict reason. The Replace strategy
+// also removes keys written by others.
+// Existing targets the source doesn't own are handled according to its conflict policy.
+func (r *ConfigMapSourceReconciler) applyTarget(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, target *corev1.ConfigMap, exists bool, desired *desiredTarget) error {
+	applied := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      target.Name,
+			Namespace: target.Namespace,
+		},
+		Data:       desired.data,
+		BinaryData: desired.binaryData,
+	}
+
+	gvk, err := apiutil.GVKForObject(configMapSource, r.Scheme)
+	if err != nil {
+		return fmt.Errorf("failed to get kind of source: %w", err)
+	}
+	fieldManager := targetFieldManager(configMapSource)
+	force := desired.strategy != "MergeFailOnConflict"
+
+	// Only ownership marks make a target the source's own, every other existing target
+	// goes through the conflict policy on each sync
+	// Targets the source synced before their ownership was tracked by label are taken over
+	// like targets it created
+	owned := !exists || isOwnedBy(target, configMapSource)
+	if !owned && syncedUntracked(configMapSource, target, fieldManager) {
+		log.FromContext(ctx).Info("Taking ownership of target synced before ownership was tracked", "namespace", target.Namespace, "name", target.Name)
+		owned = true
+	}
+	adopting := false
+	if !owned {
+		adopt, err := r.resolveTargetConflict(ctx, configMapSource, gvk.Kind, target, desired)
+		if err != nil {
+			return err
+		}
+		owned, adopting = adopt, adopt
+		if !owned && targetOwner(target) != "" {
+			// Never take keys over from the owner
+			force = false
+		}
+	}
+
+	// Set owner reference if in the same namespace, other targets are tracked by label
+	// Ownership is part of every apply, since leaving it out would remove it
+	if owned {
+		if configMapSource.GetNamespace() == target.Namespace {
+			if err := controllerutil.SetControllerReference(configMapSource, applied, r.Scheme); err != nil {
+				return fmt.Errorf("failed to set owner reference on ConfigMap: %w", err)
+			}
+		} else {
+			setOwnerLabels(applied, configMapSource, gvk.Kind)
+		}
+
+		// Keep the content from before adoption for the RestoreOriginal deletion policy
+		snapshot := target.Annotations[originalContentAnnotation]
+		if adopting {
+			if snapshot, err = snapshotTarget(configMapSource, target); err != nil {
+				return err
+			}
+		}
+		if adopting || snapshot != "" || target.Annotations[adoptedAnnotation] == "true" {
+			if applied.Annotations == nil {
+				applied.Annotations = make(map[string]string)
+			}
+			applied.Annotations[adoptedAnnotation] = "true"
+			if snapshot != "" {
+				applied.Annotations[originalContentAnnotation] = snapshot
+			}
+		}
+	}
+
+	patchOptions := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if force {
+		patchOptions = append(patchOptions, client.ForceOwnership)
+	}
+	if err := r.Patch(ctx, applied, client.Apply, patchOptions...); err != nil {
+		if apierrors.IsConflict(err) {
+			return &reasonError{reason: "Conflict", err: err}
+		}
+		return err
+	}
+	targetBytesWritten.WithLabelValues(sourceTypeLabel(configMapSource)).Add(float64(desired.size()))
+	if adopting {
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "TargetAdopted", "Adopted existing ConfigMap %s/%s", target.Namespace, target.Name)
+	}
+
+	if !desired.replacing() {
+		return nil
+	}
+
+	// Remove keys written by others, which the apply leaves alone
+	extra := desired.extraKeys(applied)
+	if len(extra) == 0 {
+		return nil
+	}
+	patch := client.MergeFrom(applied.DeepCopy())
+	for _, key := range extra {
+		delete(applied.Data, key)
+		delete(applied.BinaryData, key)
+	}
+	return r.Patch(ctx, applied, patch, client.FieldOwner(fieldManager))
+}
+
+// syncedUntracked checks if a target without owner was synced by the source before targets in
+// other namespaces were tracked by label: the source lists its namespace in status.targets,
+// and nobody but the source and the operator's plain updates from before server-side apply wrote to it
+func syncedUntracked(configMapSource configv1alpha1.GenericConfigMapSource, target *corev1.ConfigMap, fieldManager string) bool {
+	if targetOwner(target) != "" {
+		return false
+	}
+	listed := false
+	for _, synced := range configMapSource.GetStatus().Targets {
+		listed = listed || synced.Namespace == target.Namespace
+	}
+	if !listed {
+		return false
+	}
+	// The plain update of the old operator must be there, since the entries of other writers
+	// whose fields were all overwritten by the source disappear
+	legacy := false
+	for _, entry := range target.ManagedFields {
+		if entry.Manager != fieldManager && entry.Manager != legacyFieldManager {
+			return false
+		}
+		legacy = legacy || (entry.Manager == legacyFieldManager && entry.Operation == metav1.ManagedFieldsOperationUpdate)
+	}
+	return legacy
+}
@@ -0,0 +1,469 @@
+// controllers/targets.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+const (
+	// ownerUIDLabel holds the UID of the source owning a target ConfigMap that cannot have an owner reference,
+	// because it is in another namespace or the source is cluster-scoped
+	ownerUIDLabel = "configmapsource.config.example.com/owner-uid"
+	// ownerAnnotation holds the kind, namespace and name of the source owning a target ConfigMap tracked by ownerUIDLabel
+	ownerAnnotation = "configmapsource.config.example.com/owner"
+	// adoptAnnotation on an existing ConfigMap names the source, as kind/namespace/name, allowed to adopt it
+	adoptAnnotation = "configmapsource.config.example.com/adopt"
+	// ownerUIDIndex indexes sources by UID so labelled targets can be mapped back to them
+	ownerUIDIndex = ".metadata.uid"
+)
+
+// targetResult is the outcome of syncing the target ConfigMap in one namespace
+type targetResult struct {
+	// drifted is set if the live target no longer matched the source content
+	drifted bool
+	// applied is set if the source content was written to the target
+	applied bool
+	// replaced is set if the write replaced content that workloads may have started with,
+	// because the target existed before
+	replaced bool
+	// content is the content for the namespace, after templates and variables are applied
+	content *desiredTarget
+}
+
+// targetNamespaces returns the sorted namespaces the target ConfigMap is synced to
+// Namespaces selected by label are skipped while they are terminating
+func (r *ConfigMapSourceReconciler) targetNamespaces(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource) ([]string, error) {
+	namespaces := make(map[string]bool)
+	if configMapSource.GetSpec().TargetNamespace != "" {
+		namespaces[configMapSource.GetSpec().TargetNamespace] = true
+	}
+	for _, namespace := range configMapSource.GetSpec().TargetNamespaces {
+		namespaces[namespace] = true
+	}
+
+	if configMapSource.GetSpec().TargetNamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(configMapSource.GetSpec().TargetNamespaceSelector)
+		if err != nil {
+			return nil, &reasonError{reason: "InvalidNamespaceSelector", err: fmt.Errorf("invalid targetNamespaceSelector: %w", err)}
+		}
+
+		var namespaceList corev1.NamespaceList
+		if err := r.List(ctx, &namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to list target namespaces: %w", err)
+		}
+		for _, namespace := range namespaceList.Items {
+			if namespace.Status.Phase == corev1.NamespaceTerminating {
+				continue
+			}
+			namespaces[namespace.Name] = true
+		}
+	} else if len(namespaces) == 0 {
+		if configMapSource.GetNamespace() == "" {
+			return nil, errMissingNamespace
+		}
+		namespaces[configMapSource.GetNamespace()] = true
+	}
+
+	result := make([]string, 0, len(namespaces))
+	for namespace := range namespaces {
+		result = append(result, namespace)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// syncTarget brings the target ConfigMap in namespace in line with the desired content
+// The content is written if sync is set, or if the target drifted and the drift policy is Correct
+func (r *ConfigMapSourceReconciler) syncTarget(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, namespace string, desired *desiredTarget, sync bool, driftPolicy string) (targetResult, error) {
+	// Templates are rendered for each namespace, before the variables are substituted
+	// so their values are never evaluated as templates
+	if desired.renderer != nil {
+		rendered, err := desired.renderer.render(desired, namespace)
+		if err != nil {
+			return targetResult{}, err
+		}
+		desired = rendered
+	}
+	if desired.substituter != nil {
+		substituted, err := desired.substituter.apply(desired)
+		if err != nil {
+			return targetResult{}, err
+		}
+		desired = substituted
+	}
+	if configMapSource.GetSpec().Immutable != nil {
+		result, err := r.syncImmutableTarget(ctx, configMapSource, namespace, desired, sync, driftPolicy)
+		result.content = desired
+		return result, err
+	}
+	if err := r.releaseStaleImmutableTargets(ctx, configMapSource, namespace); err != nil {
+		return targetResult{}, err
+	}
+
+	logger := log.FromContext(ctx)
+	result := targetResult{content: desired}
+
+	// Get the target ConfigMap if it exists
+	var targetConfigMap corev1.ConfigMap
+	targetConfigMapName := types.NamespacedName{
+		Name:      configMapSource.GetSpec().TargetConfigMap,
+		Namespace: namespace,
+	}
+	configMapExists := true
+	if err := r.Get(ctx, targetConfigMapName, &targetConfigMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return result, fmt.Errorf("failed to get target ConfigMap: %w", err)
+		}
+		configMapExists = false
+
+		// Initialize new ConfigMap if it doesn't exist
+		targetConfigMap = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapSource.GetSpec().TargetConfigMap,
+				Namespace: namespace,
+			},
+			Data: make(map[string]string),
+		}
+	}
+
+	if !sync && driftPolicy != "Ignore" {
+		// Compare the live target against the desired content
+		result.drifted = !configMapExists || !desired.matches(&targetConfigMap)
+		if result.drifted {
+			logger.Info("Target ConfigMap has drifted from the source", "name", targetConfigMapName, "driftPolicy", driftPolicy)
+			r.recorder().Eventf(configMapSource, corev1.EventTypeWarning, "DriftDetected", "Target ConfigMap %s no longer matches the source", targetConfigMapName)
+		}
+	}
+	if !sync && (!result.drifted || driftPolicy != "Correct") {
+		return result, nil
+	}
+
+	// Apply the source content to the target ConfigMap
+	if configMapExists {
+		logger.Info("Updating existing ConfigMap", "name", targetConfigMapName)
+	} else {
+		logger.Info("Creating new ConfigMap", "name", targetConfigMapName)
+	}
+	if err := r.applyTarget(ctx, configMapSource, &targetConfigMap, configMapExists, desired); err != nil {
+		return result, err
+	}
+	result.applied = true
+	result.replaced = configMapExists
+
+	switch {
+	case result.drifted:
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "DriftCorrected", "Restored target ConfigMap %s to the source content", targetConfigMapName)
+	case configMapExists:
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "TargetUpdated", "Updated target ConfigMap %s", targetConfigMapName)
+	default:
+		r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "TargetCreated", "Created target ConfigMap %s", targetConfigMapName)
+	}
+	return result, nil
+}
+
+// removeTarget cleans up the target ConfigMap in a namespace that is no longer targeted
+// Targets owned by the ConfigMapSource are released according to its deletion policy,
+// from others only the keys it applied are withdrawn
+func (r *ConfigMapSourceReconciler) removeTarget(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, namespace string) error {
+	// Immutable targets may be left over from before spec.immutable was unset, and the
+	// in-place target from before it was set may still hold keys of the source
+	if err := r.releaseImmutableTargets(ctx, configMapSource, namespace); err != nil {
+		return err
+	}
+
+	logger := log.FromContext(ctx)
+
+	var targetConfigMap corev1.ConfigMap
+	targetConfigMapName := types.NamespacedName{
+		Name:      configMapSource.GetSpec().TargetConfigMap,
+		Namespace: namespace,
+	}
+	if err := r.Get(ctx, targetConfigMapName, &targetConfigMap); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if isOwnedBy(&targetConfigMap, configMapSource) {
+		logger.Info("Releasing ConfigMap from namespace that is no longer targeted", "name", targetConfigMapName)
+		return r.releaseTarget(ctx, configMapSource, &targetConfigMap)
+	}
+
+	return r.withdrawKeys(ctx, configMapSource, &targetConfigMap)
+}
+
+// withdrawKeys removes the keys a source applied to a target ConfigMap owned by someone else
+// Applying nothing under the source's field manager removes the keys it owns
+func (r *ConfigMapSourceReconciler) withdrawKeys(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, target *corev1.ConfigMap) error {
+	fieldManager := targetFieldManager(configMapSource)
+	applied := false
+	for _, entry := range target.ManagedFields {
+		applied = applied || (entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply)
+	}
+	if !applied {
+		return nil
+	}
+
+	targetConfigMapName := client.ObjectKeyFromObject(target)
+	log.FromContext(ctx).Info("Withdrawing source keys from ConfigMap", "name", targetConfigMapName)
+	withdrawn := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      target.Name,
+			Namespace: target.Namespace,
+		},
+	}
+	err := r.Patch(ctx, withdrawn, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	r.recorder().Eventf(configMapSource, corev1.EventTypeNormal, "KeysWithdrawn", "Withdrew source keys from ConfigMap %s", targetConfigMapName)
+	return nil
+}
+
+// requestsForNamespace returns a map function enqueueing the sources in the list returned by
+// newList whose target namespaces may change when a Namespace is created, relabelled or deleted
+func (r *ConfigMapSourceReconciler) requestsForNamespace(newList func() client.ObjectList) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		configMapSources := newList()
+		if err := r.List(ctx, configMapSources); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list ConfigMapSources for namespace", "namespace", obj.GetName())
+			return nil
+		}
+		return requestsForList(configMapSources, func(configMapSource configv1alpha1.GenericConfigMapSource) bool {
+			return targetsNamespace(configMapSource, obj)
+		})
+	}
+}
+
+// targetsNamespace reports whether a ConfigMapSource targets namespace, or did when it was last synced
+// A source selecting namespaces by label is only affected if its selector matches the labels of
+// namespace, or if it has a target there, which covers labels that stopped matching
+func targetsNamespace(configMapSource configv1alpha1.GenericConfigMapSource, namespace client.Object) bool {
+	if configMapSource.GetSpec().TargetNamespace == namespace.GetName() {
+		return true
+	}
+	for _, target := range configMapSource.GetSpec().TargetNamespaces {
+		if target == namespace.GetName() {
+			return true
+		}
+	}
+	if configMapSource.GetSpec().TargetNamespaceSelector == nil {
+		return false
+	}
+
+	for _, target := range configMapSource.GetStatus().Targets {
+		if target.Namespace == namespace.GetName() {
+			return true
+		}
+	}
+	selector, err := metav1.LabelSelectorAsSelector(configMapSource.GetSpec().TargetNamespaceSelector)
+	if err != nil {
+		// The reconcile reports the invalid selector
+		return true
+	}
+	return selector.Matches(labels.Set(namespace.GetLabels()))
+}
+
+// setOwnerLabels marks a target ConfigMap as owned by a source without an owner reference
+func setOwnerLabels(configMap *corev1.ConfigMap, configMapSource configv1alpha1.GenericConfigMapSource, kind string) {
+	if configMap.Labels == nil {
+		configMap.Labels = make(map[string]string)
+	}
+	if configMap.Annotations == nil {
+		configMap.Annotations = make(map[string]string)
+	}
+	configMap.Labels[ownerUIDLabel] = string(configMapSource.GetUID())
+	configMap.Annotations[ownerAnnotation] = sourceRef(kind, configMapSource)
+}
+
+// sourceRef identifies a source as kind/namespace/name, or kind/name if it is cluster-scoped
+func sourceRef(kind string, configMapSource configv1alpha1.GenericConfigMapSource) string {
+	return path.Join(kind, configMapSource.GetNamespace(), configMapSource.GetName())
+}
+
+// targetOwner describes the source or controller owning a target ConfigMap
+// It returns an empty string if the target has no owner
+func targetOwner(configMap *corev1.ConfigMap) string {
+	if ref := metav1.GetControllerOf(configMap); ref != nil {
+		return path.Join(ref.Kind, configMap.Namespace, ref.Name)
+	}
+	uid, ok := configMap.Labels[ownerUIDLabel]
+	if !ok {
+		return ""
+	}
+	if owner := configMap.Annotations[ownerAnnotation]; owner != "" {
+		return owner
+	}
+	return "source with UID " + uid
+}
+
+// ownedBySource checks if a target ConfigMap is owned by a ConfigMapSource or ClusterConfigMapSource
+func ownedBySource(configMap *corev1.ConfigMap) bool {
+	if _, ok := configMap.Labels[ownerUIDLabel]; ok {
+		return true
+	}
+	ref := metav1.GetControllerOf(configMap)
+	if ref == nil {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	return err == nil && gv.Group == configv1alpha1.GroupVersion.Group
+}
+
+// resolveTargetConflict decides how to write to an existing target ConfigMap the source doesn't own
+// It returns whether the source adopts the target, or a TargetConflict error if it may not write to it
+func (r *ConfigMapSourceReconciler) resolveTargetConflict(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, kind string, target *corev1.ConfigMap, desired *desiredTarget) (bool, error) {
+	owner := targetOwner(target)
+	ref := sourceRef(kind, configMapSource)
+	conflict := func(message string) error {
+		return &reasonError{reason: "TargetConflict", err: fmt.Errorf("target ConfigMap %s/%s %s", target.Namespace, target.Name, message)}
+	}
+
+	// The target names this source for adoption
+	if target.Annotations[adoptAnnotation] == ref {
+		if owner != "" {
+			return false, conflict(fmt.Sprintf("is owned by %s and cannot be adopted", owner))
+		}
+		return true, nil
+	}
+
+	// A target owned by another source is never replaced
+	if owner != "" && desired.replacing() {
+		return false, conflict(fmt.Sprintf("is owned by %s", owner))
+	}
+
+	// Merge strategies share targets owned by another source, the apply only takes over
+	// keys nobody else owns, or that have the same value
+	// An owner with the Replace strategy would remove the shared keys as drift
+	if owner != "" && ownedBySource(target) {
+		replaces, err := r.ownerReplaces(ctx, target)
+		if err != nil {
+			return false, err
+		}
+		if replaces {
+			return false, conflict(fmt.Sprintf("is owned by %s, which replaces its content", owner))
+		}
+		return false, nil
+	}
+
+	switch configMapSource.GetSpec().ConflictPolicy {
+	case "Adopt":
+		if owner != "" {
+			return false, conflict(fmt.Sprintf("is owned by %s and cannot be adopted", owner))
+		}
+		return true, nil
+	case "Overwrite":
+		return false, nil
+	default:
+		return false, conflict(fmt.Sprintf("already exists and is managed by %s, set conflictPolicy or annotate it with %s=%s to take it over",
+			describeTargetManager(target, owner), adoptAnnotation, ref))
+	}
+}
+
+// ownerReplaces checks if the source owning a target ConfigMap uses the Replace strategy
+// A target whose owner no longer exists counts as not replaced
+func (r *ConfigMapSourceReconciler) ownerReplaces(ctx context.Context, target *corev1.ConfigMap) (bool, error) {
+	var kind string
+	var key types.NamespacedName
+	var uid types.UID
+	if ref := metav1.GetControllerOf(target); ref != nil {
+		kind, key, uid = ref.Kind, types.NamespacedName{Namespace: target.Namespace, Name: ref.Name}, ref.UID
+	} else {
+		// The owner annotation is kind/namespace/name, or kind/name for cluster-scoped sources
+		parts := strings.Split(target.Annotations[ownerAnnotation], "/")
+		switch len(parts) {
+		case 2:
+			kind, key.Name = parts[0], parts[1]
+		case 3:
+			kind, key.Namespace, key.Name = parts[0], parts[1], parts[2]
+		}
+		uid = types.UID(target.Labels[ownerUIDLabel])
+	}
+
+	var owner configv1alpha1.GenericConfigMapSource
+	switch kind {
+	case "ConfigMapSource":
+		owner = &configv1alpha1.ConfigMapSource{}
+	case "ClusterConfigMapSource":
+		owner = &configv1alpha1.ClusterConfigMapSource{}
+	default:
+		return false, nil
+	}
+	if err := r.Get(ctx, key, owner); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get owner of target ConfigMap %s/%s: %w", target.Namespace, target.Name, err)
+	}
+	if owner.GetUID() != uid {
+		return false, nil
+	}
+	strategy := owner.GetSpec().MergeStrategy
+	return strategy == "" || strategy == "Replace", nil
+}
+
+// describeTargetManager names whoever manages an existing target ConfigMap
+func describeTargetManager(target *corev1.ConfigMap, owner string) string {
+	if owner != "" {
+		return owner
+	}
+	if release := target.Annotations["meta.helm.sh/release-name"]; release != "" {
+		return fmt.Sprintf("Helm release %s/%s", target.Annotations["meta.helm.sh/release-namespace"], release)
+	}
+
+	var managers []string
+	seen := make(map[string]bool)
+	for _, entry := range target.ManagedFields {
+		if !seen[entry.Manager] {
+			seen[entry.Manager] = true
+			managers = append(managers, entry.Manager)
+		}
+	}
+	if len(managers) > 0 {
+		return "field managers " + strings.Join(managers, ", ")
+	}
+	return "an unknown owner"
+}
+
+// ownerUIDIndexValue returns the UID of a source
+func ownerUIDIndexValue(obj client.Object) []string {
+	return []string{string(obj.GetUID())}
+}
+
+// requestsForOwnerLabel returns a map function enqueueing the source in the list returned
+// by newList that owns a changed target ConfigMap tracked by label
+func (r *ConfigMapSourceReconciler) requestsForOwnerLabel(newList func() client.ObjectList) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		uid, ok := obj.GetLabels()[ownerUIDLabel]
+		if !ok {
+			return nil
+		}
+
+		configMapSources := newList()
+		if err := r.List(ctx, configMapSources, client.MatchingFields{ownerUIDIndex: uid}); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list owner of target ConfigMap", "uid", uid)
+			return nil
+		}
+		return requestsForList(configMapSources, nil)
+	}
+}
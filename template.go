@@ -0,0 +1,457 @@
+// controllers/template.go
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/bmatcuk/doublestar/v4"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	configv1alpha1 "example.com/configmap-operator/api/v1alpha1"
+)
+
+const (
+	// maxRenderedBytes bounds the output of a template and of each function growing its input,
+	// it matches the maximum size of a ConfigMap
+	maxRenderedBytes = 1 << 20
+	// maxBuiltBytes bounds the strings built by the functions growing their input in one
+	// template, so calling them in a loop cannot exhaust memory either
+	maxBuiltBytes = 16 << 20
+)
+
+// templateData is the data templates are rendered with
+type templateData struct {
+	Values    map[string]string
+	Namespace string
+	Labels    map[string]string
+}
+
+// templateRenderer renders the keys of fetched content selected by spec.template
+type templateRenderer struct {
+	include []string
+	values  map[string]string
+	labels  map[string]string
+}
+
+// newTemplateRenderer loads the values of spec.template, it returns nil if templating is not enabled
+func (r *ConfigMapSourceReconciler) newTemplateRenderer(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource) (*templateRenderer, error) {
+	templateSpec := configMapSource.GetSpec().Template
+	if templateSpec == nil {
+		return nil, nil
+	}
+	for _, pattern := range templateSpec.Include {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, &reasonError{reason: "InvalidFilePattern", err: fmt.Errorf("invalid glob pattern: %s", pattern)}
+		}
+	}
+
+	values, err := r.loadValues(ctx, configMapSource, templateSpec.ValuesFrom)
+	if err != nil {
+		return nil, err
+	}
+	return &templateRenderer{
+		include: templateSpec.Include,
+		values:  values,
+		labels:  configMapSource.GetLabels(),
+	}, nil
+}
+
+// hash combines the hash of the fetched content with the inputs of the templates,
+// so a change to the values or labels is synced like a change to the content
+func (t *templateRenderer) hash(contentHash string) string {
+	inputs := map[string]string{
+		"content": contentHash,
+		"include": strings.Join(t.include, "\n"),
+	}
+	for key, value := range t.values {
+		inputs["values/"+key] = value
+	}
+	for key, value := range t.labels {
+		inputs["labels/"+key] = value
+	}
+	return calculateConfigHash(inputs, nil)
+}
+
+// render returns a copy of desired with its templates rendered for namespace
+// Errors name the key and line of the template that failed
+func (t *templateRenderer) render(desired *desiredTarget, namespace string) (*desiredTarget, error) {
+	data := templateData{
+		Values:    t.values,
+		Namespace: namespace,
+		Labels:    t.labels,
+	}
+
+	rendered := *desired
+	rendered.renderer = nil
+	rendered.data = make(map[string]string, len(desired.data))
+	for key, value := range desired.data {
+		if len(t.include) > 0 && !matchesAny(key, t.include) {
+			rendered.data[key] = value
+			continue
+		}
+
+		budget := &renderBudget{remaining: maxBuiltBytes}
+		tmpl, err := template.New(key).Option("missingkey=error").Funcs(templateFuncs).Funcs(budget.funcs()).Parse(value)
+		if err != nil {
+			return nil, &reasonError{reason: "RenderFailed", err: err}
+		}
+		var out limitedBuffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return nil, &reasonError{reason: "RenderFailed", err: err}
+		}
+		rendered.data[key] = out.String()
+	}
+	return &rendered, nil
+}
+
+// limitedBuffer is a buffer failing writes that would take it past maxRenderedBytes
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+// Write appends p to the buffer, unless the buffer would grow too large
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxRenderedBytes {
+		return 0, fmt.Errorf("rendered output is larger than %d bytes", maxRenderedBytes)
+	}
+	return b.Buffer.Write(p)
+}
+
+// renderBudget counts the bytes built by the functions growing their input in one template
+type renderBudget struct {
+	remaining int
+}
+
+// spend takes size bytes from the budget, or fails if it is used up
+func (b *renderBudget) spend(size int) error {
+	if size > b.remaining {
+		return fmt.Errorf("template builds more than %d bytes", maxBuiltBytes)
+	}
+	b.remaining -= size
+	return nil
+}
+
+// funcs returns the functions growing their input, each bounded to maxRenderedBytes
+// and drawing from the budget
+func (b *renderBudget) funcs() template.FuncMap {
+	return template.FuncMap{
+		"repeat":  b.repeat,
+		"indent":  b.indent,
+		"nindent": func(spaces int, s string) (string, error) { return b.indent(spaces, "\n"+s) },
+		"replace": b.replace,
+		"join":    b.join,
+	}
+}
+
+// repeat repeats s count times
+func (b *renderBudget) repeat(count int, s string) (string, error) {
+	if count < 0 || (len(s) > 0 && count > maxRenderedBytes/len(s)) {
+		return "", fmt.Errorf("repeat count %d out of range", count)
+	}
+	if err := b.spend(count * len(s)); err != nil {
+		return "", err
+	}
+	return strings.Repeat(s, count), nil
+}
+
+// indent prefixes every line of s with spaces
+func (b *renderBudget) indent(spaces int, s string) (string, error) {
+	lines := strings.Count(s, "\n") + 1
+	if spaces < 0 || spaces > (maxRenderedBytes-len(s))/lines {
+		return "", fmt.Errorf("indent of %d spaces out of range", spaces)
+	}
+	if err := b.spend(len(s) + spaces*lines); err != nil {
+		return "", err
+	}
+	padding := strings.Repeat(" ", spaces)
+	return padding + strings.ReplaceAll(s, "\n", "\n"+padding), nil
+}
+
+// replace replaces every occurrence of old in s with new
+func (b *renderBudget) replace(old, new, s string) (string, error) {
+	size := len(s)
+	if len(new) > len(old) {
+		count := strings.Count(s, old)
+		if old == "" {
+			count = utf8.RuneCountInString(s) + 1
+		}
+		if count > 0 && len(new)-len(old) > (maxRenderedBytes-len(s))/count {
+			return "", fmt.Errorf("result of replace is larger than %d bytes", maxRenderedBytes)
+		}
+		size += count * (len(new) - len(old))
+	}
+	if err := b.spend(size); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(s, old, new), nil
+}
+
+// join joins the items of a list with sep
+func (b *renderBudget) join(sep string, list interface{}) (string, error) {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return toString(list), nil
+	}
+	items := make([]string, value.Len())
+	size := 0
+	for i := range items {
+		items[i] = toString(value.Index(i).Interface())
+		size += len(items[i])
+		if i > 0 {
+			size += len(sep)
+		}
+		if size > maxRenderedBytes {
+			return "", fmt.Errorf("result of join is larger than %d bytes", maxRenderedBytes)
+		}
+	}
+	if err := b.spend(size); err != nil {
+		return "", err
+	}
+	return strings.Join(items, sep), nil
+}
+
+// loadValues merges the keys of the referenced ConfigMaps and Secrets, later references take precedence
+func (r *ConfigMapSourceReconciler) loadValues(ctx context.Context, configMapSource configv1alpha1.GenericConfigMapSource, references []configv1alpha1.ValuesReference) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, reference := range references {
+		referenceNamespace := reference.Namespace
+		if referenceNamespace == "" {
+			referenceNamespace = configMapSource.GetNamespace()
+		}
+		if referenceNamespace == "" {
+			return nil, errMissingNamespace
+		}
+		name := types.NamespacedName{Namespace: referenceNamespace, Name: reference.Name}
+
+		var err error
+		switch reference.Kind {
+		case "ConfigMap":
+			var configMap corev1.ConfigMap
+			if err = r.Get(ctx, name, &configMap); err == nil {
+				for key, value := range configMap.Data {
+					values[key] = value
+				}
+				for key, value := range configMap.BinaryData {
+					values[key] = string(value)
+				}
+			}
+		case "Secret":
+			var secret corev1.Secret
+			if err = r.Get(ctx, name, &secret); err == nil {
+				for key, value := range secret.Data {
+					values[key] = string(value)
+				}
+			}
+		default:
+			return nil, &reasonError{reason: "InvalidValuesReference", err: fmt.Errorf("unsupported values kind: %s", reference.Kind)}
+		}
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				if reference.Optional {
+					continue
+				}
+				return nil, &reasonError{reason: "ValuesNotFound", err: fmt.Errorf("%s %s not found", reference.Kind, name)}
+			}
+			return nil, fmt.Errorf("failed to get %s %s: %w", reference.Kind, name, err)
+		}
+	}
+	return values, nil
+}
+
+// valuesReferences returns the ConfigMaps and Secrets a ConfigMapSource reads values or variables from
+func valuesReferences(configMapSource configv1alpha1.GenericConfigMapSource) []configv1alpha1.ValuesReference {
+	var references []configv1alpha1.ValuesReference
+	if configMapSource.GetSpec().Template != nil {
+		references = append(references, configMapSource.GetSpec().Template.ValuesFrom...)
+	}
+	if configMapSource.GetSpec().Substitution != nil {
+		references = append(references, configMapSource.GetSpec().Substitution.VariablesFrom...)
+	}
+	return references
+}
+
+// templateFuncs is a subset of the Sprig functions, with the same names and argument order
+// Functions reading the environment, the network or the clock are left out, so rendering
+// only depends on the content and the values
+// repeat, indent, nindent, replace and join are added per template by renderBudget.funcs
+var templateFuncs = template.FuncMap{
+	// Defaults
+	"default":  defaultValue,
+	"empty":    empty,
+	"coalesce": coalesce,
+	"required": required,
+	"ternary":  ternary,
+
+	// Strings
+	"quote":      func(s interface{}) string { return strconv.Quote(toString(s)) },
+	"squote":     func(s interface{}) string { return "'" + toString(s) + "'" },
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+	"toString":   toString,
+	"atoi":       func(s string) int { i, _ := strconv.Atoi(s); return i },
+
+	// Lists and dicts
+	"list":   func(items ...interface{}) []interface{} { return items },
+	"dict":   dict,
+	"hasKey": hasKey,
+	"keys":   keys,
+
+	// Encoding
+	"toJson":    toJSON,
+	"toYaml":    toYAML,
+	"b64enc":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec":    b64dec,
+	"sha256sum": func(s string) string { sum := sha256.Sum256([]byte(s)); return hex.EncodeToString(sum[:]) },
+}
+
+// defaultValue returns given, or def if given is empty
+func defaultValue(def interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+// empty reports whether a value is nil or the zero value of its type
+func empty(given interface{}) bool {
+	value := reflect.ValueOf(given)
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	default:
+		return value.IsZero()
+	}
+}
+
+// coalesce returns the first value that is not empty
+func coalesce(values ...interface{}) interface{} {
+	for _, value := range values {
+		if !empty(value) {
+			return value
+		}
+	}
+	return nil
+}
+
+// required fails rendering with message if value is empty
+func required(message string, value interface{}) (interface{}, error) {
+	if empty(value) {
+		return nil, errors.New(message)
+	}
+	return value, nil
+}
+
+// ternary returns trueValue if condition is set, falseValue otherwise
+func ternary(trueValue, falseValue interface{}, condition bool) interface{} {
+	if condition {
+		return trueValue
+	}
+	return falseValue
+}
+
+// toString formats a value as a string
+func toString(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// dict builds a map from alternating keys and values
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict needs an even number of arguments")
+	}
+	result := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		result[toString(pairs[i])] = pairs[i+1]
+	}
+	return result, nil
+}
+
+// hasKey reports whether a map with string keys has key
+func hasKey(m interface{}, key string) bool {
+	value := reflect.ValueOf(m)
+	if value.Kind() != reflect.Map || value.Type().Key().Kind() != reflect.String {
+		return false
+	}
+	return value.MapIndex(reflect.ValueOf(key).Convert(value.Type().Key())).IsValid()
+}
+
+// keys returns the sorted keys of a map
+func keys(m interface{}) ([]string, error) {
+	value := reflect.ValueOf(m)
+	if value.Kind() != reflect.Map {
+		return nil, fmt.Errorf("keys needs a map, got %T", m)
+	}
+	result := make([]string, 0, value.Len())
+	for _, key := range value.MapKeys() {
+		result = append(result, toString(key.Interface()))
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// toJSON encodes a value as JSON
+func toJSON(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// toYAML encodes a value as YAML, without a trailing newline
+func toYAML(value interface{}) (string, error) {
+	encoded, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(encoded), "\n"), nil
+}
+
+// b64dec decodes standard base64
+func b64dec(s string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}